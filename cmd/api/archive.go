@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+)
+
+const autoArchiveBatchSize = 500
+
+// autoArchive archives the done tasks that are due under their owner's
+// auto-archive rule, a batch at a time.
+func (app *application) autoArchive(ctx context.Context) error {
+	for ctx.Err() == nil {
+		archived, err := app.models.Tasks.AutoArchive(autoArchiveBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if archived < autoArchiveBatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodyBytes caps the body of requests sent with an
+	// Idempotency-Key, which is read in full to be hashed.
+	maxIdempotentBodyBytes = 1_048_576
+)
+
+// idempotent makes POST requests sent with an Idempotency-Key header safe to
+// retry. The first request with a key runs and its response is stored for
+// the user; a retry with the same method, URL and body gets that response
+// back instead of running again. Reusing a key for a different request is a
+// 422. Server errors aren't stored, so the request can be retried. Only
+// requests without a body or with a JSON one are covered: uploads are
+// streamed and go straight through.
+func (app *application) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || key == "" || !hasJSONBody(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			app.badRequestResponse(w, r, fmt.Errorf("Idempotency-Key must not be longer than %d bytes", maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				app.errorResponse(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("requests with an Idempotency-Key must not be larger than %d bytes", maxIdempotentBodyBytes))
+				return
+			}
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.RequestURI())
+		hash.Write(body)
+
+		user := ctx.ContextGetUser(r)
+
+		stored, err := app.models.IdempotencyKeys.Reserve(user.ID, key, hash.Sum(nil), app.config.idempotency.ttl)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrIdempotencyKeyReused):
+				app.faildErrorResponse(w, r, map[string]string{"idempotency_key": "was already used for a different request"})
+			case errors.Is(err, data.ErrIdempotencyKeyInProgress):
+				app.errorResponse(w, r, http.StatusConflict, "a request with this Idempotency-Key is still being processed, retry later")
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if stored != nil {
+			for name, values := range stored.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		saved := false
+
+		// a failed or panicking request frees the key for a retry
+		defer func() {
+			if saved {
+				return
+			}
+			if err := app.models.IdempotencyKeys.Release(user.ID, key); err != nil {
+				app.logError(r, err)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status >= http.StatusInternalServerError {
+			return
+		}
+
+		err = app.models.IdempotencyKeys.Save(user.ID, key, &data.StoredResponse{
+			Status: rec.status,
+			Header: rec.Header().Clone(),
+			Body:   rec.body.Bytes(),
+		})
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+		saved = true
+	})
+}
+
+// hasJSONBody reports whether the request has no body or a JSON one.
+func hasJSONBody(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return r.ContentLength == 0
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+const idempotencyPurgeBatchSize = 1000
+
+// purgeIdempotencyKeys removes the stored responses that can no longer be
+// replayed, a batch at a time.
+func (app *application) purgeIdempotencyKeys(ctx context.Context) error {
+	for ctx.Err() == nil {
+		purged, err := app.models.IdempotencyKeys.DeleteExpired(app.config.idempotency.ttl, idempotencyPurgeBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if purged < idempotencyPurgeBatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
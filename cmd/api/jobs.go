@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// runPeriodically runs job now and then every interval until ctx is
+// cancelled. It runs through app.background, so serve() waits for the run in
+// progress to finish before the process exits.
+func (app *application) runPeriodically(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context) error) {
+	app.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			err := app.runJob(ctx, job)
+			if err != nil {
+				app.logger.Error(err.Error(), "job", name)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// runJob turns a panic in job into an error, so one bad run doesn't stop
+// the job for good.
+func (app *application) runJob(ctx context.Context, job func(ctx context.Context) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+
+	return job(ctx)
+}
+
+// startJobs starts the enabled background jobs. They stop once ctx is
+// cancelled.
+func (app *application) startJobs(ctx context.Context) {
+	if app.config.reminders.enabled {
+		app.runPeriodically(ctx, "reminders", app.config.reminders.interval, app.sendDueReminders)
+	}
+	if app.config.trash.purgeEnabled {
+		app.runPeriodically(ctx, "trash", app.config.trash.purgeInterval, app.purgeTrash)
+	}
+	if app.config.autoArchive.enabled {
+		app.runPeriodically(ctx, "auto-archive", app.config.autoArchive.interval, app.autoArchive)
+	}
+	if app.config.idempotency.purgeEnabled {
+		app.runPeriodically(ctx, "idempotency-keys", app.config.idempotency.purgeInterval, app.purgeIdempotencyKeys)
+	}
+}
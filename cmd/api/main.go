@@ -2,16 +2,19 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/moutafatin/go-tasks-management-api/internal/data"
 	"github.com/moutafatin/go-tasks-management-api/internal/env"
 	"github.com/moutafatin/go-tasks-management-api/internal/handlers"
 	"github.com/moutafatin/go-tasks-management-api/internal/mailer"
 	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/storage"
 	"github.com/subosito/gotenv"
 )
 
@@ -37,6 +40,31 @@ type config struct {
 		password string
 		sender   string
 	}
+	storage struct {
+		dir string
+	}
+	attachments struct {
+		maxFileMB   int
+		userQuotaMB int
+	}
+	reminders struct {
+		enabled  bool
+		interval time.Duration
+	}
+	trash struct {
+		retention     time.Duration
+		purgeEnabled  bool
+		purgeInterval time.Duration
+	}
+	autoArchive struct {
+		enabled  bool
+		interval time.Duration
+	}
+	idempotency struct {
+		ttl           time.Duration
+		purgeEnabled  bool
+		purgeInterval time.Duration
+	}
 }
 
 type application struct {
@@ -44,6 +72,7 @@ type application struct {
 	logger   *slog.Logger
 	config   config
 	mailer   mailer.Mailer
+	storage  storage.Store
 	error    response.ErrorResponse
 	wg       sync.WaitGroup
 	env      currentEnv
@@ -73,10 +102,33 @@ func main() {
 	flag.StringVar(&cfg.smtp.password, "smtp-password", env.GetString("SMTP_PASSWORD", ""), "SMTP password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", env.GetString("SMTP_SENDER", ""), "SMTP sender")
 
+	flag.StringVar(&cfg.storage.dir, "storage-dir", env.GetString("STORAGE_DIR", "./uploads"), "Directory for uploaded attachments")
+	flag.IntVar(&cfg.attachments.maxFileMB, "attachments-max-file-mb", env.GetInt("ATTACHMENTS_MAX_FILE_MB", 25), "Maximum size of a single attachment in MB")
+	flag.IntVar(&cfg.attachments.userQuotaMB, "attachments-user-quota-mb", env.GetInt("ATTACHMENTS_USER_QUOTA_MB", 500), "Maximum total size of a user's attachments in MB")
+
+	flag.BoolVar(&cfg.reminders.enabled, "reminders-enabled", env.GetBool("REMINDERS_ENABLED", true), "Send task reminders from this process")
+	flag.DurationVar(&cfg.reminders.interval, "reminders-interval", env.GetDuration("REMINDERS_INTERVAL", time.Minute), "How often to poll for due reminders")
+
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", env.GetDuration("TRASH_RETENTION", 30*24*time.Hour), "How long deleted tasks stay in the trash before they are purged")
+	flag.BoolVar(&cfg.trash.purgeEnabled, "trash-purge-enabled", env.GetBool("TRASH_PURGE_ENABLED", true), "Purge expired tasks from the trash from this process")
+	flag.DurationVar(&cfg.trash.purgeInterval, "trash-purge-interval", env.GetDuration("TRASH_PURGE_INTERVAL", time.Hour), "How often to purge the trash")
+
+	flag.BoolVar(&cfg.autoArchive.enabled, "auto-archive-enabled", env.GetBool("AUTO_ARCHIVE_ENABLED", true), "Apply the users' auto-archive rules from this process")
+	flag.DurationVar(&cfg.autoArchive.interval, "auto-archive-interval", env.GetDuration("AUTO_ARCHIVE_INTERVAL", time.Hour), "How often to apply the auto-archive rules")
+
+	flag.DurationVar(&cfg.idempotency.ttl, "idempotency-ttl", env.GetDuration("IDEMPOTENCY_TTL", 24*time.Hour), "How long responses to requests with an Idempotency-Key are kept for retries")
+	flag.BoolVar(&cfg.idempotency.purgeEnabled, "idempotency-purge-enabled", env.GetBool("IDEMPOTENCY_PURGE_ENABLED", true), "Purge expired idempotency keys from this process")
+	flag.DurationVar(&cfg.idempotency.purgeInterval, "idempotency-purge-interval", env.GetDuration("IDEMPOTENCY_PURGE_INTERVAL", time.Hour), "How often to purge expired idempotency keys")
+
 	flag.Parse()
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+	if err := cfg.validate(); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	db, err := openDb(cfg.db)
 	if err != nil {
 		logger.Error(err.Error())
@@ -86,22 +138,35 @@ func main() {
 
 	logger.Info("Connected to database")
 
+	store, err := storage.NewLocal(cfg.storage.dir)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	errorResponse := response.ErrorResponse{
 		Logger: logger,
 	}
 
 	models := data.NewModels(db)
 	app := &application{
-		models: data.NewModels(db),
-		logger: logger,
-		config: cfg,
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
-		error:  errorResponse, env: currentEnv(cfg.environment),
-		handlers: handlers.New(handlers.Config{
-			Error:  errorResponse,
-			Models: models,
-		}),
+		models:  models,
+		logger:  logger,
+		config:  cfg,
+		mailer:  mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		storage: store,
+		error:   errorResponse, env: currentEnv(cfg.environment),
 	}
+	app.handlers = handlers.New(handlers.Config{
+		Error:      errorResponse,
+		Models:     models,
+		Mailer:     app.mailer,
+		Background: app.background,
+		Storage:    store,
+
+		MaxAttachmentSize: int64(cfg.attachments.maxFileMB) << 20,
+		AttachmentQuota:   int64(cfg.attachments.userQuotaMB) << 20,
+	})
 
 	err = app.serve()
 	if err != nil {
@@ -109,3 +174,27 @@ func main() {
 	}
 	os.Exit(1)
 }
+
+// validate rejects durations a job can't run with: a ticker panics on an
+// interval of 0, and a retention of 0 would empty the trash at once.
+func (cfg config) validate() error {
+	durations := []struct {
+		flag  string
+		value time.Duration
+	}{
+		{"reminders-interval", cfg.reminders.interval},
+		{"trash-retention", cfg.trash.retention},
+		{"trash-purge-interval", cfg.trash.purgeInterval},
+		{"auto-archive-interval", cfg.autoArchive.interval},
+		{"idempotency-ttl", cfg.idempotency.ttl},
+		{"idempotency-purge-interval", cfg.idempotency.purgeInterval},
+	}
+
+	for _, d := range durations {
+		if d.value <= 0 {
+			return fmt.Errorf("-%s must be a positive duration, got %s", d.flag, d.value)
+		}
+	}
+
+	return nil
+}
@@ -134,3 +134,70 @@ func (app *application) requireActivatedUser(next http.Handler) http.Handler {
 
 	return app.requireAuthenticatedUser(fn)
 }
+
+// requireTaskRole only lets through users whose role on the task in the {id}
+// URL parameter includes min. Users who can't see the task get a 404.
+func (app *application) requireTaskRole(min data.ProjectRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := app.readIntParam(r, "id")
+			if err != nil {
+				app.badRequestResponse(w, r, ErrInvalidIdParam)
+				return
+			}
+
+			user := ctx.ContextGetUser(r)
+
+			role, err := app.models.Tasks.Role(id, user.ID)
+			if err != nil {
+				if errors.Is(err, data.ErrRecordNotFound) {
+					app.notFoundResponse(w, r, "task not found")
+					return
+				}
+
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			if !role.Includes(min) {
+				app.notPermittedResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireProjectRole is the project counterpart of requireTaskRole.
+func (app *application) requireProjectRole(min data.ProjectRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := app.readIntParam(r, "id")
+			if err != nil {
+				app.badRequestResponse(w, r, ErrInvalidIdParam)
+				return
+			}
+
+			user := ctx.ContextGetUser(r)
+
+			role, err := app.models.Projects.Role(id, user.ID)
+			if err != nil {
+				if errors.Is(err, data.ErrRecordNotFound) {
+					app.notFoundResponse(w, r, "project not found")
+					return
+				}
+
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			if !role.Includes(min) {
+				app.notPermittedResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
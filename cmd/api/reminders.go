@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+const reminderBatchSize = 50
+
+// sendDueReminders emails every reminder that is due, a batch at a time. A
+// started batch is always finished, so shutting down doesn't leave claimed
+// reminders unsent.
+func (app *application) sendDueReminders(ctx context.Context) error {
+	for ctx.Err() == nil {
+		reminders, err := app.models.Reminders.ClaimDue(reminderBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, reminder := range reminders {
+			data := map[string]any{
+				"name":      reminder.Name,
+				"taskID":    reminder.TaskID,
+				"taskTitle": reminder.TaskTitle,
+				"dueAt":     "",
+			}
+			if reminder.DueAt != nil {
+				data["dueAt"] = reminder.DueAt.Format(time.RFC1123)
+			}
+
+			err = app.mailer.Send(reminder.Email, "task_reminder.tmpl", data)
+			if err != nil {
+				app.logger.Error(err.Error(), "reminder_id", reminder.ID)
+
+				if err := app.models.Reminders.Release(reminder.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(reminders) < reminderBatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
 )
 
 func (app *application) routes() http.Handler {
@@ -16,12 +17,99 @@ func (app *application) routes() http.Handler {
 
 	r.Group(func(r chi.Router) {
 		r.Use(app.requireActivatedUser)
+		r.Use(app.idempotent)
+
+		canReadTask := app.requireTaskRole(data.RoleViewer)
+		canWriteTask := app.requireTaskRole(data.RoleEditor)
 
 		r.Get("/api/v1/tasks", app.handlers.Tasks.HandleGetTasks)
-		r.Get("/api/v1/tasks/{id}", app.handlers.Tasks.HandleGetTaskByID)
-		r.Delete("/api/v1/tasks/{id}", app.handlers.Tasks.HandleDeleteTask)
-		r.Put("/api/v1/tasks/{id}", app.handlers.Tasks.HandleUpdateTask)
+		r.With(canReadTask).Get("/api/v1/tasks/{id}", app.handlers.Tasks.HandleGetTaskByID)
+		r.With(canWriteTask).Delete("/api/v1/tasks/{id}", app.handlers.Tasks.HandleDeleteTask)
+		r.With(canWriteTask).Put("/api/v1/tasks/{id}", app.handlers.Tasks.HandleUpdateTask)
+		r.With(canWriteTask).Patch("/api/v1/tasks/{id}", app.handlers.Tasks.HandlePatchTask)
 		r.Post("/api/v1/tasks", app.handlers.Tasks.HandleCreateTask)
+		r.Post("/api/v1/tasks/bulk", app.handlers.Tasks.HandleBulkTasks)
+
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/children", app.handlers.Tasks.HandleGetTaskChildren)
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/tree", app.handlers.Tasks.HandleGetTaskTree)
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/history", app.handlers.Tasks.HandleGetTaskHistory)
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/occurrences", app.handlers.Tasks.HandleGetTaskOccurrences)
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/reminders", app.handlers.Tasks.HandleGetTaskReminders)
+		r.With(canReadTask).Post("/api/v1/tasks/{id}/reminders", app.handlers.Tasks.HandleCreateTaskReminder)
+		r.With(canReadTask).Delete("/api/v1/tasks/{id}/reminders/{reminderID}", app.handlers.Tasks.HandleDeleteTaskReminder)
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/blockers", app.handlers.Tasks.HandleGetTaskBlockers)
+		r.With(canWriteTask).Post("/api/v1/tasks/{id}/blockers", app.handlers.Tasks.HandleAddTaskBlocker)
+		r.With(canWriteTask).Delete("/api/v1/tasks/{id}/blockers/{blockerID}", app.handlers.Tasks.HandleRemoveTaskBlocker)
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/checklist", app.handlers.Tasks.HandleGetChecklist)
+		r.With(canWriteTask).Post("/api/v1/tasks/{id}/checklist", app.handlers.Tasks.HandleCreateChecklistItem)
+		r.With(canWriteTask).Put("/api/v1/tasks/{id}/checklist/order", app.handlers.Tasks.HandleReorderChecklist)
+		r.With(canWriteTask).Put("/api/v1/tasks/{id}/checklist/{itemID}", app.handlers.Tasks.HandleUpdateChecklistItem)
+		r.With(canWriteTask).Post("/api/v1/tasks/{id}/checklist/{itemID}/toggle", app.handlers.Tasks.HandleToggleChecklistItem)
+		r.With(canWriteTask).Delete("/api/v1/tasks/{id}/checklist/{itemID}", app.handlers.Tasks.HandleDeleteChecklistItem)
+		r.With(canWriteTask).Post("/api/v1/tasks/{id}/move", app.handlers.Tasks.HandleMoveTaskOnBoard)
+		r.With(canWriteTask).Post("/api/v1/tasks/{id}/archive", app.handlers.Tasks.HandleArchiveTask)
+		r.With(canWriteTask).Post("/api/v1/tasks/{id}/unarchive", app.handlers.Tasks.HandleUnarchiveTask)
+		r.With(canWriteTask).Put("/api/v1/tasks/{id}/parent", app.handlers.Tasks.HandleMoveTaskParent)
+		r.With(canReadTask).Post("/api/v1/tasks/{id}/labels", app.handlers.Tasks.HandleAttachTaskLabels)
+		r.With(canReadTask).Delete("/api/v1/tasks/{id}/labels/{labelID}", app.handlers.Tasks.HandleDetachTaskLabel)
+		r.With(canWriteTask).Put("/api/v1/tasks/{id}/assignee", app.handlers.Tasks.HandleAssignTask)
+		r.With(canWriteTask).Delete("/api/v1/tasks/{id}/assignee", app.handlers.Tasks.HandleUnassignTask)
+
+		r.Get("/api/v1/tasks/{id}/comments", app.handlers.Comments.HandleGetComments)
+		r.Post("/api/v1/tasks/{id}/comments", app.handlers.Comments.HandleCreateComment)
+		r.Put("/api/v1/tasks/{id}/comments/{commentID}", app.handlers.Comments.HandleUpdateComment)
+		r.Delete("/api/v1/tasks/{id}/comments/{commentID}", app.handlers.Comments.HandleDeleteComment)
+
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/attachments", app.handlers.Attachments.HandleGetAttachments)
+		r.With(canWriteTask).Post("/api/v1/tasks/{id}/attachments", app.handlers.Attachments.HandleUploadAttachment)
+		r.With(canReadTask).Get("/api/v1/tasks/{id}/attachments/{attachmentID}", app.handlers.Attachments.HandleDownloadAttachment)
+		r.With(canWriteTask).Delete("/api/v1/tasks/{id}/attachments/{attachmentID}", app.handlers.Attachments.HandleDeleteAttachment)
+
+		r.Get("/api/v1/trash", app.handlers.Tasks.HandleGetTrash)
+		r.Post("/api/v1/trash/{id}/restore", app.handlers.Tasks.HandleRestoreTask)
+		r.Delete("/api/v1/trash/{id}", app.handlers.Tasks.HandleDeleteTaskPermanently)
+
+		r.Get("/api/v1/board", app.handlers.Tasks.HandleGetBoard)
+		r.Get("/api/v1/search", app.handlers.Tasks.HandleSearch)
+		r.Get("/api/v1/dependencies", app.handlers.Tasks.HandleGetDependencyGraph)
+		r.Get("/api/v1/me/assigned", app.handlers.Tasks.HandleGetAssignedTasks)
+		r.Get("/api/v1/me/activity", app.handlers.Tasks.HandleGetActivity)
+		r.Get("/api/v1/me/settings", app.handleGetSettings)
+		r.Put("/api/v1/me/settings", app.handleUpdateSettings)
+		r.Get("/api/v1/me/workflow", app.handlers.Workflows.HandleGetWorkflow)
+		r.Put("/api/v1/me/workflow", app.handlers.Workflows.HandleReplaceWorkflow)
+		r.Delete("/api/v1/me/workflow", app.handlers.Workflows.HandleResetWorkflow)
+
+		r.Get("/api/v1/labels", app.handlers.Labels.HandleGetLabels)
+		r.Post("/api/v1/labels", app.handlers.Labels.HandleCreateLabel)
+		r.Get("/api/v1/labels/{id}", app.handlers.Labels.HandleGetLabelByID)
+		r.Put("/api/v1/labels/{id}", app.handlers.Labels.HandleUpdateLabel)
+		r.Delete("/api/v1/labels/{id}", app.handlers.Labels.HandleDeleteLabel)
+
+		isProjectMember := app.requireProjectRole(data.RoleViewer)
+		isProjectOwner := app.requireProjectRole(data.RoleOwner)
+
+		r.Get("/api/v1/projects", app.handlers.Projects.HandleGetProjects)
+		r.Post("/api/v1/projects", app.handlers.Projects.HandleCreateProject)
+		r.With(isProjectMember).Get("/api/v1/projects/{id}", app.handlers.Projects.HandleGetProjectByID)
+		r.With(isProjectOwner).Put("/api/v1/projects/{id}", app.handlers.Projects.HandleUpdateProject)
+		r.With(isProjectOwner).Delete("/api/v1/projects/{id}", app.handlers.Projects.HandleDeleteProject)
+		r.With(isProjectMember).Get("/api/v1/projects/{id}/tasks", app.handlers.Tasks.HandleGetProjectTasks)
+		r.With(isProjectMember).Get("/api/v1/projects/{id}/fields", app.handlers.Projects.HandleGetCustomFields)
+		r.With(isProjectOwner).Post("/api/v1/projects/{id}/fields", app.handlers.Projects.HandleCreateCustomField)
+		r.With(isProjectOwner).Put("/api/v1/projects/{id}/fields/{fieldID}", app.handlers.Projects.HandleUpdateCustomField)
+		r.With(isProjectOwner).Delete("/api/v1/projects/{id}/fields/{fieldID}", app.handlers.Projects.HandleDeleteCustomField)
+		r.With(isProjectMember).Get("/api/v1/projects/{id}/workflow", app.handlers.Workflows.HandleGetWorkflow)
+		r.With(isProjectOwner).Put("/api/v1/projects/{id}/workflow", app.handlers.Workflows.HandleReplaceWorkflow)
+		r.With(isProjectOwner).Delete("/api/v1/projects/{id}/workflow", app.handlers.Workflows.HandleResetWorkflow)
+
+		r.With(isProjectMember).Get("/api/v1/projects/{id}/members", app.handlers.Projects.HandleGetProjectMembers)
+		r.With(isProjectOwner).Put("/api/v1/projects/{id}/members/{userID}", app.handlers.Projects.HandleUpdateProjectMember)
+		r.With(isProjectMember).Delete("/api/v1/projects/{id}/members/{userID}", app.handlers.Projects.HandleDeleteProjectMember)
+		r.With(isProjectOwner).Get("/api/v1/projects/{id}/invitations", app.handlers.Projects.HandleGetProjectInvitations)
+		r.With(isProjectOwner).Post("/api/v1/projects/{id}/invitations", app.handlers.Projects.HandleCreateProjectInvitation)
+		r.With(isProjectOwner).Delete("/api/v1/projects/{id}/invitations/{invitationID}", app.handlers.Projects.HandleDeleteProjectInvitation)
+		r.Post("/api/v1/invitations/accept", app.handlers.Projects.HandleAcceptInvitation)
 	})
 
 	r.Post("/api/v1/users", app.handleRegisterUser)
@@ -29,6 +29,9 @@ func (app *application) serve() error {
 
 	shutDownErrCh := make(chan error)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
 	go func() {
 		quitCh := make(chan os.Signal, 1)
 
@@ -44,12 +47,16 @@ func (app *application) serve() error {
 			shutDownErrCh <- err
 		}
 
+		stopJobs()
+
 		app.logger.Info("completing background tasks", "addr", srv.Addr)
 
 		app.wg.Wait()
 		shutDownErrCh <- nil
 	}()
 
+	app.startJobs(jobsCtx)
+
 	app.logger.Info(app.getEnvBasedUrl())
 
 	var err error
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+)
+
+const trashPurgeBatchSize = 100
+
+// purgeTrash removes the tasks that have been in the trash for longer than
+// the retention period, a batch at a time, along with their attachments.
+func (app *application) purgeTrash(ctx context.Context) error {
+	for ctx.Err() == nil {
+		keys, purged, err := app.models.Tasks.PurgeTrash(app.config.trash.retention, trashPurgeBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := app.storage.Delete(ctx, key); err != nil {
+				app.logger.Error(err.Error(), "storage_key", key)
+			}
+		}
+
+		if purged < trashPurgeBatchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
 	"github.com/moutafatin/go-tasks-management-api/internal/data"
 	"github.com/moutafatin/go-tasks-management-api/internal/request"
 	"github.com/moutafatin/go-tasks-management-api/internal/response"
@@ -127,3 +128,55 @@ func (app *application) handleActivateUser(w http.ResponseWriter, r *http.Reques
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	user := ctx.ContextGetUser(r)
+
+	err := response.JSON(w, http.StatusOK, envelope{"settings": envelope{"auto_archive_days": user.AutoArchiveDays}})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// handleUpdateSettings changes the current user's settings. auto_archive_days
+// archives their tasks once they have been done for that many days, 0 turns
+// it off.
+func (app *application) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		AutoArchiveDays *int `json:"auto_archive_days"`
+	}
+
+	err := request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	if input.AutoArchiveDays != nil {
+		user.AutoArchiveDays = *input.AutoArchiveDays
+	}
+
+	v := validator.New()
+
+	if data.ValidateUser(v, user); !v.Valid() {
+		app.faildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Users.Update(user)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			app.editConflictResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, envelope{"settings": envelope{"auto_archive_days": user.AutoArchiveDays}})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -8,14 +8,12 @@ require (
 )
 
 require (
+	github.com/go-mail/mail/v2 v2.3.0
 	github.com/jackc/pgerrcode v0.0.0-20220416144525-469b46aa5efa
 	golang.org/x/time v0.5.0
 )
 
-require (
-	github.com/go-mail/mail/v2 v2.3.0 // indirect
-	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
-)
+require gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
 
 require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect
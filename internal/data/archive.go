@@ -0,0 +1,196 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// taskDoneSince returns the SQL expression for when a task of alias last
+// changed status, which is when a done task was finished. Tasks without a
+// recorded status change fall back to their creation time.
+func taskDoneSince(alias string) string {
+	return `COALESCE((SELECT max(e.created_at) FROM task_events e WHERE e.task_id = ` + alias + `.id AND e.type = 'status_changed'), ` + alias + `.created_at)`
+}
+
+// Archive moves the task and its subtasks to the archive on behalf of
+// userID. Archived tasks are kept out of the default listing and the board
+// but stay readable and editable. Archiving an archived task is a no-op.
+func (t *tasksModel) Archive(id, userID int) (*Task, error) {
+	var task Task
+
+	err := pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		var found bool
+
+		stmt := `SELECT true FROM tasks t WHERE t.id = $1 AND ` + taskWritableBy("t", "$2") + ` FOR UPDATE`
+		err := tx.QueryRow(context.Background(), stmt, id, userID).Scan(&found)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		subtree, err := taskSubtree(tx, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(context.Background(), `UPDATE tasks SET archived_at = now(), version = version + 1 WHERE id = ANY($1) AND archived_at IS NULL AND deleted_at IS NULL RETURNING id`, append(subtree, id))
+		if err != nil {
+			return err
+		}
+
+		archived, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		if err := recordTaskEvents(tx, archived, userID, TaskArchived, nil); err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(context.Background(), `SELECT `+taskColumns("$2")+` FROM tasks t WHERE t.id = $1`, id, userID).Scan(task.scanFields()...)
+		if err != nil {
+			return err
+		}
+		task.afterScan()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// Unarchive brings a task back from the archive on behalf of userID, with the
+// subtasks archived along with it, at the end of their board column.
+func (t *tasksModel) Unarchive(id, userID int) (*Task, error) {
+	var task Task
+
+	err := pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		var current struct {
+			projectID  *int
+			ownerID    int
+			archivedAt *time.Time
+		}
+
+		stmt := `SELECT t.project_id, t.user_id, t.archived_at FROM tasks t WHERE t.id = $1 AND ` + taskWritableBy("t", "$2") + ` FOR UPDATE`
+		err := tx.QueryRow(context.Background(), stmt, id, userID).Scan(&current.projectID, &current.ownerID, &current.archivedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		if current.archivedAt != nil {
+			if err := lockBoard(tx, current.projectID, current.ownerID); err != nil {
+				return err
+			}
+
+			stmt = `
+WITH RECURSIVE unarchived AS (
+  SELECT id FROM tasks WHERE id = $1
+  UNION ALL
+  SELECT c.id FROM tasks c INNER JOIN unarchived u ON c.parent_id = u.id WHERE c.archived_at = $2 AND c.deleted_at IS NULL
+)
+UPDATE tasks t SET archived_at = NULL, version = t.version + 1 FROM unarchived u WHERE t.id = u.id
+RETURNING t.id, t.status`
+
+			rows, err := tx.Query(context.Background(), stmt, id, *current.archivedAt)
+			if err != nil {
+				return err
+			}
+
+			type unarchivedTask struct {
+				ID     int
+				Status TaskStatus
+			}
+
+			unarchived, err := pgx.CollectRows(rows, pgx.RowToStructByPos[unarchivedTask])
+			if err != nil {
+				return err
+			}
+
+			ids := make([]int, len(unarchived))
+			for i, u := range unarchived {
+				ids[i] = u.ID
+
+				position, err := positionBetween(tx, current.projectID, current.ownerID, u.Status, u.ID, nil, nil)
+				if err != nil {
+					return err
+				}
+
+				if _, err := tx.Exec(context.Background(), `UPDATE tasks SET position = $1, version = version + 1 WHERE id = $2`, position, u.ID); err != nil {
+					return err
+				}
+			}
+
+			if err := recordTaskEvents(tx, ids, userID, TaskUnarchived, nil); err != nil {
+				return err
+			}
+		}
+
+		err = tx.QueryRow(context.Background(), `SELECT `+taskColumns("$2")+` FROM tasks t WHERE t.id = $1`, id, userID).Scan(task.scanFields()...)
+		if err != nil {
+			return err
+		}
+		task.afterScan()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// AutoArchive archives up to limit done tasks whose owner has turned on
+// auto-archiving and that have been done for longer than the owner's
+// setting. Rows locked by another instance are skipped. It returns the
+// number of tasks archived.
+func (t *tasksModel) AutoArchive(limit int) (int, error) {
+	var archived []int
+
+	err := pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		stmt := `
+WITH due AS (
+  SELECT t.id
+  FROM tasks t
+  INNER JOIN users u ON u.id = t.user_id
+  WHERE u.auto_archive_days > 0 AND t.archived_at IS NULL AND t.deleted_at IS NULL
+    AND ` + taskIsDone("t") + `
+    AND ` + taskDoneSince("t") + ` < now() - make_interval(days => u.auto_archive_days)
+  ORDER BY t.id
+  LIMIT $1
+  FOR UPDATE OF t SKIP LOCKED
+)
+UPDATE tasks t SET archived_at = now(), version = t.version + 1
+FROM due
+WHERE t.id = due.id
+RETURNING t.id`
+
+		rows, err := tx.Query(context.Background(), stmt, limit)
+		if err != nil {
+			return err
+		}
+
+		archived, err = pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		return recordTaskEvents(tx, archived, 0, TaskArchived, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(archived), nil
+}
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrAssigneeNotMember = errors.New("assignee can't access the task")
+
+// Assign makes assigneeID responsible for the task on behalf of userID, who
+// needs write access to it. The assignee must be able to read the task: the
+// owner of a personal task, or any member of the task's project. A nil
+// assigneeID unassigns the task. It returns the previous assignee.
+func (t *tasksModel) Assign(id, userID int, assigneeID *int) (*int, error) {
+	var previous *int
+
+	err := pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		stmt := `SELECT t.assignee_id FROM tasks t WHERE t.id = $1 AND ` + taskWritableBy("t", "$2") + ` FOR UPDATE`
+
+		err := tx.QueryRow(context.Background(), stmt, id, userID).Scan(&previous)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		if assigneeID != nil {
+			var allowed bool
+
+			stmt = `SELECT EXISTS (SELECT 1 FROM tasks t WHERE t.id = $1 AND ` + taskReadableBy("t", "$2") + `)`
+			err = tx.QueryRow(context.Background(), stmt, id, *assigneeID).Scan(&allowed)
+			if err != nil {
+				return err
+			}
+
+			if !allowed {
+				return ErrAssigneeNotMember
+			}
+		}
+
+		_, err = tx.Exec(context.Background(), `UPDATE tasks SET assignee_id = $1, version = version + 1 WHERE id = $2`, assigneeID, id)
+		if err != nil {
+			return err
+		}
+
+		if equalIntPtr(previous, assigneeID) {
+			return nil
+		}
+
+		return recordTaskEvents(tx, []int{id}, userID, TaskUpdated, map[string]FieldChange{"assignee_id": {From: previous, To: assigneeID}})
+	})
+
+	return previous, err
+}
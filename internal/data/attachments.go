@@ -0,0 +1,156 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+var ErrQuotaExceeded = errors.New("attachment quota exceeded")
+
+type Attachment struct {
+	ID          int       `json:"id"`
+	TaskID      int       `json:"task_id"`
+	UserID      int       `json:"user_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type attachmentsModel struct {
+	DB *pgxpool.Pool
+}
+
+const attachmentColumns = `id, task_id, user_id, filename, content_type, size, sha256, storage_key, created_at`
+
+func (attachment *Attachment) scanFields() []any {
+	return []any{&attachment.ID, &attachment.TaskID, &attachment.UserID, &attachment.Filename, &attachment.ContentType, &attachment.Size, &attachment.SHA256, &attachment.StorageKey, &attachment.CreatedAt}
+}
+
+// NewAttachmentKey returns a random storage key for a new attachment on
+// taskID. Keys never reuse the client's file name.
+func NewAttachmentKey(taskID int) (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("tasks/%d/%s", taskID, hex.EncodeToString(randomBytes)), nil
+}
+
+// GetAllForTask lists a task's attachments, newest first. Callers must have
+// checked that the user can read the task.
+func (a attachmentsModel) GetAllForTask(taskID int) ([]*Attachment, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM task_attachments WHERE task_id = $1 ORDER BY created_at DESC, id DESC`
+
+	rows, err := a.DB.Query(context.Background(), stmt, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*Attachment{}
+
+	for rows.Next() {
+		var attachment Attachment
+		if err := rows.Scan(attachment.scanFields()...); err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, &attachment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (a attachmentsModel) Get(id, taskID int) (*Attachment, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM task_attachments WHERE id = $1 AND task_id = $2`
+
+	var attachment Attachment
+	err := a.DB.QueryRow(context.Background(), stmt, id, taskID).Scan(attachment.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// Usage returns the number of bytes userID has uploaded across all tasks.
+func (a attachmentsModel) Usage(userID int) (int64, error) {
+	var usage int64
+	err := a.DB.QueryRow(context.Background(), `SELECT COALESCE(sum(size), 0) FROM task_attachments WHERE user_id = $1`, userID).Scan(&usage)
+
+	return usage, err
+}
+
+// Insert records an uploaded attachment, failing with ErrQuotaExceeded if it
+// would take the uploader over quota bytes. Concurrent uploads by the same
+// user are serialized so they can't both slip under the quota.
+func (a attachmentsModel) Insert(attachment *Attachment, quota int64) error {
+	return pgx.BeginFunc(context.Background(), a.DB, func(tx pgx.Tx) error {
+		_, err := tx.Exec(context.Background(), `SELECT pg_advisory_xact_lock(hashtext('task_attachments'), $1)`, attachment.UserID)
+		if err != nil {
+			return err
+		}
+
+		var usage int64
+		err = tx.QueryRow(context.Background(), `SELECT COALESCE(sum(size), 0) FROM task_attachments WHERE user_id = $1`, attachment.UserID).Scan(&usage)
+		if err != nil {
+			return err
+		}
+
+		if usage+attachment.Size > quota {
+			return ErrQuotaExceeded
+		}
+
+		stmt := `
+INSERT INTO task_attachments (task_id, user_id, filename, content_type, size, sha256, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at`
+		args := []any{attachment.TaskID, attachment.UserID, attachment.Filename, attachment.ContentType, attachment.Size, attachment.SHA256, attachment.StorageKey}
+
+		return tx.QueryRow(context.Background(), stmt, args...).Scan(&attachment.ID, &attachment.CreatedAt)
+	})
+}
+
+// Delete removes an attachment's metadata and returns its storage key so the
+// caller can remove the blob.
+func (a attachmentsModel) Delete(id, taskID int) (string, error) {
+	var key string
+	err := a.DB.QueryRow(context.Background(), `DELETE FROM task_attachments WHERE id = $1 AND task_id = $2 RETURNING storage_key`, id, taskID).Scan(&key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrRecordNotFound
+		}
+		return "", err
+	}
+
+	return key, nil
+}
+
+func ValidateAttachment(v *validator.Validator, attachment *Attachment) {
+	v.Check(validator.NotEmpty(attachment.Filename), "filename", "must be provided")
+	v.Check(len(attachment.Filename) <= 255, "filename", "must not be more than 255 bytes long")
+	if attachment.UserID < 1 || attachment.TaskID < 1 {
+		panic("invalid operation,attachment can't exist without a task and a user")
+	}
+}
@@ -0,0 +1,265 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/moutafatin/go-tasks-management-api/internal/rank"
+)
+
+var ErrBoardNeighbour = errors.New("neighbouring task is not in the target column")
+
+// BoardColumn is one status column of a board. A board holds the tasks of a
+// project, or a user's tasks outside of any project, with a column per state
+// of its workflow, and Task.Position orders the tasks within their column.
+// Archived tasks are off the board.
+type BoardColumn struct {
+	Status TaskStatus `json:"status"`
+	Tasks  []*Task    `json:"tasks"`
+}
+
+// inBoard returns the SQL condition restricting alias to the board of the
+// project behind projectArg, or of the user behind userArg when the project
+// is NULL.
+func inBoard(alias, projectArg, userArg string) string {
+	return "(" + alias + ".project_id = " + projectArg + " OR (" + projectArg + "::int IS NULL AND " + alias + ".project_id IS NULL AND " + alias + ".user_id = " + userArg + "))"
+}
+
+// lockBoard serializes position changes on a board.
+func lockBoard(db dbtx, projectID *int, userID int) error {
+	key := -userID
+	if projectID != nil {
+		key = *projectID
+	}
+
+	_, err := db.Exec(context.Background(), `SELECT pg_advisory_xact_lock(hashtext('task_board'), $1)`, key)
+	return err
+}
+
+// nextPosition returns a position at the end of a board column. Callers must
+// hold the board lock.
+func nextPosition(db dbtx, projectID *int, userID int, status TaskStatus) (string, error) {
+	return positionBetween(db, projectID, userID, status, 0, nil, nil)
+}
+
+// positionBetween returns a position for taskID in a board column, right
+// after afterID or right before beforeID, between both when both are given,
+// and at the end of the column when neither is. The column is respread when
+// its keys collide or grow too long. Callers must hold the board lock.
+func positionBetween(db dbtx, projectID *int, userID int, status TaskStatus, taskID int, afterID, beforeID *int) (string, error) {
+	for attempt := 0; ; attempt++ {
+		a, b, err := neighbourPositions(db, projectID, userID, status, taskID, afterID, beforeID)
+		if err != nil {
+			return "", err
+		}
+
+		position, err := rank.Between(a, b)
+		if err == nil && len(position) <= rank.MaxLength {
+			return position, nil
+		}
+		if err != nil && !errors.Is(err, rank.ErrInvalidRange) {
+			return "", err
+		}
+		if attempt > 0 {
+			if err == nil {
+				return position, nil
+			}
+			// the explicit neighbours are in the wrong order
+			return "", ErrBoardNeighbour
+		}
+
+		if err := respreadColumn(db, projectID, userID, status); err != nil {
+			return "", err
+		}
+	}
+}
+
+// neighbourPositions returns the positions the new position must fall
+// between, "" standing for either end of the column. taskID itself is
+// ignored, so a task can be moved within its own column.
+func neighbourPositions(db dbtx, projectID *int, userID int, status TaskStatus, taskID int, afterID, beforeID *int) (string, string, error) {
+	column := inBoard("t", "$1", "$2") + ` AND t.status = $3 AND t.id <> $4 AND t.deleted_at IS NULL AND t.archived_at IS NULL`
+	args := []any{projectID, userID, status, taskID}
+
+	position := func(id int) (string, error) {
+		var p string
+		err := db.QueryRow(context.Background(), `SELECT t.position FROM tasks t WHERE `+column+` AND t.id = $5`, append(args, id)...).Scan(&p)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrBoardNeighbour
+		}
+		return p, err
+	}
+
+	var a, b string
+	var err error
+
+	if afterID != nil {
+		if a, err = position(*afterID); err != nil {
+			return "", "", err
+		}
+	}
+	if beforeID != nil {
+		if b, err = position(*beforeID); err != nil {
+			return "", "", err
+		}
+	}
+
+	switch {
+	case afterID != nil && beforeID == nil:
+		err = db.QueryRow(context.Background(), `SELECT COALESCE(min(t.position), '') FROM tasks t WHERE `+column+` AND t.position > $5`, append(args, a)...).Scan(&b)
+	case afterID == nil && beforeID != nil:
+		err = db.QueryRow(context.Background(), `SELECT COALESCE(max(t.position), '') FROM tasks t WHERE `+column+` AND t.position < $5`, append(args, b)...).Scan(&a)
+	case afterID == nil && beforeID == nil:
+		err = db.QueryRow(context.Background(), `SELECT COALESCE(max(t.position), '') FROM tasks t WHERE `+column, args...).Scan(&a)
+	}
+
+	return a, b, err
+}
+
+// respreadColumn gives every task of a board column a fresh, short position,
+// keeping their order.
+func respreadColumn(db dbtx, projectID *int, userID int, status TaskStatus) error {
+	rows, err := db.Query(context.Background(), `SELECT t.id FROM tasks t WHERE `+inBoard("t", "$1", "$2")+` AND t.status = $3 AND t.deleted_at IS NULL AND t.archived_at IS NULL ORDER BY t.position, t.id`, projectID, userID, status)
+	if err != nil {
+		return err
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+UPDATE tasks t SET position = p.position, version = t.version + 1
+FROM unnest($1::int[], $2::text[]) AS p(id, position)
+WHERE t.id = p.id`
+
+	_, err = db.Exec(context.Background(), stmt, ids, rank.Spread(len(ids)))
+	return err
+}
+
+// GetBoard returns the board of a project, or of the user's tasks outside of
+// any project when projectID is nil.
+func (t *tasksModel) GetBoard(userID int, projectID *int) ([]*BoardColumn, error) {
+	stmt := `
+SELECT ` + taskColumns("$2") + `
+FROM tasks t
+WHERE ` + inBoard("t", "$1", "$2") + ` AND ` + taskReadableBy("t", "$2") + ` AND t.archived_at IS NULL
+ORDER BY t.position, t.id`
+
+	workflow, err := workflowFor(t.DB, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := t.DB.Query(context.Background(), stmt, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]*BoardColumn, len(workflow.States))
+	byStatus := make(map[TaskStatus]*BoardColumn, len(workflow.States))
+
+	for i, state := range workflow.States {
+		columns[i] = &BoardColumn{Status: state.Name, Tasks: []*Task{}}
+		byStatus[state.Name] = columns[i]
+	}
+
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(task.scanFields()...); err != nil {
+			return nil, err
+		}
+		task.afterScan()
+
+		if column, ok := byStatus[task.Status]; ok {
+			column.Tasks = append(column.Tasks, &task)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+// MoveOnBoard moves a task to the status column and, within it, right after
+// afterID and/or right before beforeID, in one step. Moving it to the end of
+// the column only takes the status. The same rules as Update apply to the
+// status change.
+func (t *tasksModel) MoveOnBoard(id, userID int, status TaskStatus, afterID, beforeID *int) (*Task, error) {
+	var task Task
+
+	err := pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		var current struct {
+			projectID *int
+			ownerID   int
+			status    TaskStatus
+			done      bool
+		}
+
+		stmt := `SELECT t.project_id, t.user_id, t.status, ` + taskIsDone("t") + ` FROM tasks t WHERE t.id = $1 AND ` + taskWritableBy("t", "$2") + ` FOR UPDATE`
+		err := tx.QueryRow(context.Background(), stmt, id, userID).Scan(&current.projectID, &current.ownerID, &current.status, &current.done)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		if err := lockBoard(tx, current.projectID, current.ownerID); err != nil {
+			return err
+		}
+
+		workflow, err := workflowFor(tx, current.projectID, current.ownerID)
+		if err != nil {
+			return err
+		}
+		if err := checkWorkflowStatus(workflow, current.status, status); err != nil {
+			return err
+		}
+
+		if status != current.status && workflow.needsUnblocked(status) {
+			if err := checkUnblocked(tx, id); err != nil {
+				return err
+			}
+		}
+
+		position, err := positionBetween(tx, current.projectID, current.ownerID, status, id, afterID, beforeID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(context.Background(), `UPDATE tasks SET status = $1, position = $2, version = version + 1 WHERE id = $3`, status, position, id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(context.Background(), `SELECT `+taskColumns("$2")+` FROM tasks t WHERE t.id = $1`, id, userID).Scan(task.scanFields()...)
+		if err != nil {
+			return err
+		}
+		task.afterScan()
+
+		if status != current.status {
+			err = recordTaskEvents(tx, []int{id}, userID, TaskStatusChanged, map[string]FieldChange{"status": {From: current.status, To: status}})
+			if err != nil {
+				return err
+			}
+		}
+
+		if task.Recurrence != nil && !current.done && workflow.IsTerminal(status) {
+			return t.insertNextOccurrence(tx, &task, userID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// MaxBulkTasks caps the number of tasks a bulk operation works on.
+const MaxBulkTasks = 100
+
+// TaskValidationError is returned for a task a bulk change would leave
+// invalid. Errors holds the failed checks, as ValidateTask reports them.
+type TaskValidationError struct {
+	Errors map[string]string
+}
+
+func (e *TaskValidationError) Error() string {
+	return "task is invalid"
+}
+
+// BulkResult is the outcome of a bulk operation on one task. When Err is set
+// the task was left as it was and Task is nil.
+type BulkResult struct {
+	ID   int
+	Task *Task
+	Err  error
+}
+
+// BulkUpdate applies change to each of the tasks ids on behalf of userID,
+// within a single transaction. Every task goes through the same checks as
+// Update, ValidateTask included, in a savepoint of its own: a task that
+// can't be changed gets an error in its result while the others are saved.
+// Any other error rolls the whole batch back.
+func (t *tasksModel) BulkUpdate(ids []int, userID int, change func(task *Task)) ([]BulkResult, error) {
+	return bulk(t.DB, ids, func(tx pgx.Tx, id int) (*Task, error) {
+		task, err := getTask(tx, id, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		change(task)
+
+		workflow, err := workflowFor(tx, task.ProjectID, task.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		v := validator.New()
+		if ValidateTask(v, task, workflow); !v.Valid() {
+			return nil, &TaskValidationError{Errors: v.Errors}
+		}
+
+		if err := t.update(tx, task, userID); err != nil {
+			return nil, err
+		}
+
+		return task, nil
+	})
+}
+
+// BulkDelete moves each of the tasks ids to the trash on behalf of userID,
+// within a single transaction, like BulkUpdate does.
+func (t *tasksModel) BulkDelete(ids []int, userID int) ([]BulkResult, error) {
+	return bulk(t.DB, ids, func(tx pgx.Tx, id int) (*Task, error) {
+		return nil, deleteTask(tx, id, 0, userID)
+	})
+}
+
+// bulk runs op on each of ids, once per id, in a savepoint of a single
+// transaction and collects the per-task errors.
+func bulk(db dbtx, ids []int, op func(tx pgx.Tx, id int) (*Task, error)) ([]BulkResult, error) {
+	results := make([]BulkResult, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+
+	err := pgx.BeginFunc(context.Background(), db, func(tx pgx.Tx) error {
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			result := BulkResult{ID: id}
+
+			err := pgx.BeginFunc(context.Background(), tx, func(tx pgx.Tx) error {
+				var err error
+				result.Task, err = op(tx, id)
+				return err
+			})
+			if err != nil {
+				if !isTaskError(err) {
+					return err
+				}
+				result.Task, result.Err = nil, err
+			}
+
+			results = append(results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// isTaskError reports whether err is about the task an operation was given,
+// rather than a failure of the operation itself.
+func isTaskError(err error) bool {
+	var blocked *BlockedError
+	var invalid *TaskValidationError
+
+	switch {
+	case errors.As(err, &blocked), errors.As(err, &invalid):
+		return true
+	}
+
+	for _, target := range []error{
+		ErrRecordNotFound, ErrParentNotFound, ErrTaskCycle, ErrTaskTooDeep, ErrParentProject,
+		ErrProjectNotFound, ErrProjectReadOnly, ErrUnknownStatus, ErrTransitionNotAllowed, ErrEditConflict,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,256 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+const MaxChecklistItems = 200
+
+var (
+	ErrChecklistOrder = errors.New("checklist order must list every item exactly once")
+	ErrChecklistFull  = errors.New("checklist is full")
+)
+
+type ChecklistItem struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	Text      string    `json:"text"`
+	Checked   bool      `json:"checked"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChecklistProgress is rendered in JSON as "checked/total", e.g. "3/5".
+type ChecklistProgress struct {
+	Checked int
+	Total   int
+}
+
+func (p ChecklistProgress) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"%d/%d"`, p.Checked, p.Total)), nil
+}
+
+type checklistsModel struct {
+	DB *pgxpool.Pool
+}
+
+const checklistColumns = `id, task_id, text, checked, position, created_at, updated_at`
+
+func (item *ChecklistItem) scanFields() []any {
+	return []any{&item.ID, &item.TaskID, &item.Text, &item.Checked, &item.Position, &item.CreatedAt, &item.UpdatedAt}
+}
+
+// lockChecklist locks the task row, so changes to the positions of its
+// checklist items don't interleave, and bumps the task's version, as the
+// task shows the progress of its checklist.
+func lockChecklist(tx pgx.Tx, taskID int) error {
+	var id int
+	err := tx.QueryRow(context.Background(), `UPDATE tasks SET version = version + 1 WHERE id = $1 RETURNING id`, taskID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+
+	return err
+}
+
+// GetAll returns a task's checklist in position order.
+func (c checklistsModel) GetAll(taskID int) ([]*ChecklistItem, error) {
+	rows, err := c.DB.Query(context.Background(), `SELECT `+checklistColumns+` FROM task_checklist_items WHERE task_id = $1 ORDER BY position, id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*ChecklistItem{}
+
+	for rows.Next() {
+		var item ChecklistItem
+		if err := rows.Scan(item.scanFields()...); err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, rows.Err()
+}
+
+// Insert adds the item at position, shifting the following items down, or at
+// the end of the checklist when position is nil or past the end.
+func (c checklistsModel) Insert(item *ChecklistItem, position *int) error {
+	return pgx.BeginFunc(context.Background(), c.DB, func(tx pgx.Tx) error {
+		if err := lockChecklist(tx, item.TaskID); err != nil {
+			return err
+		}
+
+		var count int
+		err := tx.QueryRow(context.Background(), `SELECT count(*) FROM task_checklist_items WHERE task_id = $1`, item.TaskID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count >= MaxChecklistItems {
+			return ErrChecklistFull
+		}
+
+		item.Position = count
+		if position != nil && *position < count {
+			item.Position = *position
+
+			_, err = tx.Exec(context.Background(), `UPDATE task_checklist_items SET position = position + 1 WHERE task_id = $1 AND position >= $2`, item.TaskID, item.Position)
+			if err != nil {
+				return err
+			}
+		}
+
+		stmt := `
+INSERT INTO task_checklist_items (task_id, text, checked, position)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at, updated_at`
+
+		return tx.QueryRow(context.Background(), stmt, item.TaskID, item.Text, item.Checked, item.Position).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	})
+}
+
+func (c checklistsModel) Get(id, taskID int) (*ChecklistItem, error) {
+	var item ChecklistItem
+	err := c.DB.QueryRow(context.Background(), `SELECT `+checklistColumns+` FROM task_checklist_items WHERE id = $1 AND task_id = $2`, id, taskID).Scan(item.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (c checklistsModel) Update(item *ChecklistItem) error {
+	return pgx.BeginFunc(context.Background(), c.DB, func(tx pgx.Tx) error {
+		if err := lockChecklist(tx, item.TaskID); err != nil {
+			return err
+		}
+
+		stmt := `
+UPDATE task_checklist_items SET text = $1, checked = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $3 AND task_id = $4
+RETURNING updated_at`
+
+		err := tx.QueryRow(context.Background(), stmt, item.Text, item.Checked, item.ID, item.TaskID).Scan(&item.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+
+		return err
+	})
+}
+
+// Toggle flips an item's checked state in place, so concurrent toggles don't
+// need to read the item first.
+func (c checklistsModel) Toggle(id, taskID int) (*ChecklistItem, error) {
+	var item ChecklistItem
+
+	err := pgx.BeginFunc(context.Background(), c.DB, func(tx pgx.Tx) error {
+		if err := lockChecklist(tx, taskID); err != nil {
+			return err
+		}
+
+		stmt := `
+UPDATE task_checklist_items SET checked = NOT checked, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND task_id = $2
+RETURNING ` + checklistColumns
+
+		err := tx.QueryRow(context.Background(), stmt, id, taskID).Scan(item.scanFields()...)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// Delete removes an item and closes the gap it leaves in the positions.
+func (c checklistsModel) Delete(id, taskID int) error {
+	return pgx.BeginFunc(context.Background(), c.DB, func(tx pgx.Tx) error {
+		if err := lockChecklist(tx, taskID); err != nil {
+			return err
+		}
+
+		var position int
+		err := tx.QueryRow(context.Background(), `DELETE FROM task_checklist_items WHERE id = $1 AND task_id = $2 RETURNING position`, id, taskID).Scan(&position)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		_, err = tx.Exec(context.Background(), `UPDATE task_checklist_items SET position = position - 1 WHERE task_id = $1 AND position > $2`, taskID, position)
+		return err
+	})
+}
+
+// Reorder puts the checklist in the order of ids, which must list each of
+// the task's items exactly once.
+func (c checklistsModel) Reorder(taskID int, ids []int) error {
+	return pgx.BeginFunc(context.Background(), c.DB, func(tx pgx.Tx) error {
+		if err := lockChecklist(tx, taskID); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(context.Background(), `SELECT id FROM task_checklist_items WHERE task_id = $1 ORDER BY id`, taskID)
+		if err != nil {
+			return err
+		}
+
+		current, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		sorted := slices.Clone(ids)
+		slices.Sort(sorted)
+		if !slices.Equal(current, sorted) {
+			return ErrChecklistOrder
+		}
+
+		stmt := `
+UPDATE task_checklist_items c SET position = o.ord - 1, updated_at = CURRENT_TIMESTAMP
+FROM unnest($2::int[]) WITH ORDINALITY AS o(id, ord)
+WHERE c.id = o.id AND c.task_id = $1`
+
+		_, err = tx.Exec(context.Background(), stmt, taskID, ids)
+		return err
+	})
+}
+
+// copyChecklist gives a task's next occurrence the same checklist, unchecked.
+func copyChecklist(db dbtx, fromID, toID int) error {
+	stmt := `
+INSERT INTO task_checklist_items (task_id, text, position)
+SELECT $2, text, position FROM task_checklist_items WHERE task_id = $1`
+
+	_, err := db.Exec(context.Background(), stmt, fromID, toID)
+	return err
+}
+
+func ValidateChecklistItem(v *validator.Validator, item *ChecklistItem) {
+	v.Check(validator.NotEmpty(item.Text), "text", "must be provided")
+	v.Check(len(item.Text) <= 500, "text", "must not be more than 500 bytes long")
+	if item.TaskID < 1 {
+		panic("invalid operation,checklist item can't exist without a task")
+	}
+}
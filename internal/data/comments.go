@@ -0,0 +1,146 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/markdown"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+type Comment struct {
+	ID         int       `json:"id"`
+	TaskID     int       `json:"task_id"`
+	UserID     int       `json:"user_id"`
+	AuthorName string    `json:"author_name"`
+	Body       string    `json:"body"`
+	BodyHTML   string    `json:"body_html"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type commentsModel struct {
+	DB *pgxpool.Pool
+}
+
+const commentColumns = `c.id, c.task_id, c.user_id, u.name, c.body, c.created_at, c.updated_at`
+
+func (comment *Comment) scanFields() []any {
+	return []any{&comment.ID, &comment.TaskID, &comment.UserID, &comment.AuthorName, &comment.Body, &comment.CreatedAt, &comment.UpdatedAt}
+}
+
+// GetAllForTask lists a task's comments, oldest first. Callers must have
+// checked that the user can read the task.
+func (c commentsModel) GetAllForTask(taskID int, filters Filters) ([]*Comment, Metadata, error) {
+	stmt := `
+SELECT count(*) OVER(), ` + commentColumns + `
+FROM task_comments c
+INNER JOIN users u ON u.id = c.user_id
+WHERE c.task_id = $1
+ORDER BY c.created_at, c.id
+LIMIT $2 OFFSET $3`
+
+	rows, err := c.DB.Query(context.Background(), stmt, taskID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	comments := []*Comment{}
+
+	for rows.Next() {
+		var comment Comment
+		if err := rows.Scan(append([]any{&totalRecords}, comment.scanFields()...)...); err != nil {
+			return nil, Metadata{}, err
+		}
+		comment.BodyHTML = markdown.Render(comment.Body)
+
+		comments = append(comments, &comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return comments, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (c commentsModel) Get(id, taskID int) (*Comment, error) {
+	stmt := `
+SELECT ` + commentColumns + `
+FROM task_comments c
+INNER JOIN users u ON u.id = c.user_id
+WHERE c.id = $1 AND c.task_id = $2`
+
+	var comment Comment
+	err := c.DB.QueryRow(context.Background(), stmt, id, taskID).Scan(comment.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	comment.BodyHTML = markdown.Render(comment.Body)
+
+	return &comment, nil
+}
+
+func (c commentsModel) Insert(comment *Comment) error {
+	stmt := `
+INSERT INTO task_comments (task_id, user_id, body)
+VALUES ($1, $2, $3)
+RETURNING id, created_at, updated_at, (SELECT name FROM users WHERE id = $2)`
+
+	err := c.DB.QueryRow(context.Background(), stmt, comment.TaskID, comment.UserID, comment.Body).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt, &comment.AuthorName)
+	if err != nil {
+		return err
+	}
+	comment.BodyHTML = markdown.Render(comment.Body)
+
+	return nil
+}
+
+// Update saves the comment's body. Only its author can change it.
+func (c commentsModel) Update(comment *Comment) error {
+	stmt := `
+UPDATE task_comments SET body = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2 AND task_id = $3 AND user_id = $4
+RETURNING updated_at`
+
+	err := c.DB.QueryRow(context.Background(), stmt, comment.Body, comment.ID, comment.TaskID, comment.UserID).Scan(&comment.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+	comment.BodyHTML = markdown.Render(comment.Body)
+
+	return nil
+}
+
+// Delete removes a comment. Only its author can delete it.
+func (c commentsModel) Delete(id, taskID, userID int) error {
+	res, err := c.DB.Exec(context.Background(), `DELETE FROM task_comments WHERE id = $1 AND task_id = $2 AND user_id = $3`, id, taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() != 1 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func ValidateComment(v *validator.Validator, comment *Comment) {
+	v.Check(validator.NotEmpty(comment.Body), "body", "must be provided")
+	v.Check(len(comment.Body) <= 10_000, "body", "must not be more than 10000 bytes long")
+	if comment.UserID < 1 || comment.TaskID < 1 {
+		panic("invalid operation,comment can't exist without a task and a user")
+	}
+}
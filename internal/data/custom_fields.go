@@ -0,0 +1,401 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+var ErrDuplicateField = errors.New("duplicate custom field")
+
+type CustomFieldType string
+
+const (
+	FieldText         CustomFieldType = "text"
+	FieldNumber       CustomFieldType = "number"
+	FieldDate         CustomFieldType = "date"
+	FieldSingleSelect CustomFieldType = "single_select"
+	FieldMultiSelect  CustomFieldType = "multi_select"
+	FieldURL          CustomFieldType = "url"
+	FieldUser         CustomFieldType = "user"
+)
+
+var customFieldTypes = []CustomFieldType{FieldText, FieldNumber, FieldDate, FieldSingleSelect, FieldMultiSelect, FieldURL, FieldUser}
+
+const (
+	MaxCustomFields      = 50
+	maxFieldOptions      = 100
+	maxFieldValueLength  = 2000
+	customFieldKeyPrefix = "field."
+)
+
+var fieldKeyRX = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// CustomField is a typed field defined by a project. Task values are stored
+// in tasks.custom_fields under the field's key: strings for text, date
+// (YYYY-MM-DD), single_select and url fields, numbers for number and user
+// fields, and string arrays for multi_select fields.
+type CustomField struct {
+	ID        int             `json:"id"`
+	ProjectID int             `json:"project_id"`
+	Key       string          `json:"key"`
+	Name      string          `json:"name"`
+	Type      CustomFieldType `json:"type"`
+	Options   []string        `json:"options"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// value returns the SQL expression of the field's value on a task of alias,
+// as text, or as numeric for number fields.
+func (field *CustomField) value(alias string) string {
+	if field.Type == FieldNumber {
+		return fmt.Sprintf("CASE WHEN jsonb_typeof(%[1]s.custom_fields->'%[2]s') = 'number' THEN (%[1]s.custom_fields->>'%[2]s')::numeric END", alias, field.Key)
+	}
+	return fmt.Sprintf("(%s.custom_fields->>'%s')", alias, field.Key)
+}
+
+type customFieldsModel struct {
+	DB *pgxpool.Pool
+}
+
+const customFieldColumns = `f.id, f.project_id, f.key, f.name, f.type, f.options, f.created_at`
+
+func (cf customFieldsModel) GetAll(projectID int) ([]*CustomField, error) {
+	rows, err := cf.DB.Query(context.Background(), `SELECT `+customFieldColumns+` FROM custom_fields f WHERE f.project_id = $1 ORDER BY f.position, f.id`, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[CustomField])
+}
+
+func (cf customFieldsModel) Get(id, projectID int) (*CustomField, error) {
+	rows, err := cf.DB.Query(context.Background(), `SELECT `+customFieldColumns+` FROM custom_fields f WHERE f.id = $1 AND f.project_id = $2`, id, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByPos[CustomField])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return field, nil
+}
+
+// Insert adds the field after the project's other fields.
+func (cf customFieldsModel) Insert(field *CustomField) error {
+	stmt := `
+INSERT INTO custom_fields (project_id, key, name, type, options, position)
+VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(max(position) + 1, 0) FROM custom_fields WHERE project_id = $1))
+RETURNING id, created_at`
+
+	args := []any{field.ProjectID, field.Key, field.Name, field.Type, field.Options}
+
+	err := cf.DB.QueryRow(context.Background(), stmt, args...).Scan(&field.ID, &field.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrDuplicateField
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update saves the field's name and options. Select values using an option
+// that was removed are cleared from the project's tasks.
+func (cf customFieldsModel) Update(field *CustomField) error {
+	return pgx.BeginFunc(context.Background(), cf.DB, func(tx pgx.Tx) error {
+		res, err := tx.Exec(context.Background(), `UPDATE custom_fields SET name = $1, options = $2 WHERE id = $3 AND project_id = $4`, field.Name, field.Options, field.ID, field.ProjectID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() != 1 {
+			return ErrRecordNotFound
+		}
+
+		var stmt string
+
+		switch field.Type {
+		case FieldSingleSelect:
+			stmt = `
+UPDATE tasks SET custom_fields = custom_fields - $2::text, version = version + 1
+WHERE project_id = $1 AND custom_fields ? $2 AND NOT (custom_fields->>$2 = ANY($3::text[]))`
+		case FieldMultiSelect:
+			stmt = `
+UPDATE tasks SET custom_fields = jsonb_set(custom_fields, ARRAY[$2::text],
+  COALESCE((SELECT jsonb_agg(o) FROM jsonb_array_elements_text(custom_fields->$2) o WHERE o = ANY($3::text[])), '[]')),
+  version = version + 1
+WHERE project_id = $1 AND custom_fields ? $2`
+		default:
+			return nil
+		}
+
+		_, err = tx.Exec(context.Background(), stmt, field.ProjectID, field.Key, field.Options)
+		return err
+	})
+}
+
+// Delete removes the field and its values on the project's tasks.
+func (cf customFieldsModel) Delete(id, projectID int) error {
+	return pgx.BeginFunc(context.Background(), cf.DB, func(tx pgx.Tx) error {
+		var key string
+
+		err := tx.QueryRow(context.Background(), `DELETE FROM custom_fields WHERE id = $1 AND project_id = $2 RETURNING key`, id, projectID).Scan(&key)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		_, err = tx.Exec(context.Background(), `UPDATE tasks SET custom_fields = custom_fields - $2::text, version = version + 1 WHERE project_id = $1 AND custom_fields ? $2`, projectID, key)
+		return err
+	})
+}
+
+// fitCustomFields keeps, on the tasks ids, the values of the fields their
+// project has with the same key and type. It runs once they have moved.
+func fitCustomFields(db dbtx, ids []int) error {
+	stmt := `
+UPDATE tasks t SET custom_fields = (
+  SELECT COALESCE(jsonb_object_agg(e.key, e.value), '{}')
+  FROM jsonb_each(t.custom_fields) e
+  INNER JOIN custom_fields f ON f.project_id = t.project_id AND f.key = e.key
+  WHERE jsonb_typeof(e.value) = CASE f.type WHEN 'number' THEN 'number' WHEN 'user' THEN 'number' WHEN 'multi_select' THEN 'array' ELSE 'string' END
+), version = t.version + 1
+WHERE t.id = ANY($1) AND t.custom_fields <> '{}'`
+
+	_, err := db.Exec(context.Background(), stmt, ids)
+	return err
+}
+
+// FieldFilter filters the task list on a custom field, from the
+// field.<key>, field.<key>.gte and field.<key>.lte query parameters.
+type FieldFilter struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+// Operators of a FieldFilter.
+const (
+	FieldOpEq  = "eq"
+	FieldOpGte = "gte"
+	FieldOpLte = "lte"
+)
+
+// ParseFieldFilter reads a field.<key>[.op] query parameter name.
+func ParseFieldFilter(param, value string) (FieldFilter, bool) {
+	name, ok := strings.CutPrefix(param, customFieldKeyPrefix)
+	if !ok {
+		return FieldFilter{}, false
+	}
+
+	key, op, _ := strings.Cut(name, ".")
+	if op == "" {
+		op = FieldOpEq
+	}
+
+	return FieldFilter{Key: key, Op: op, Value: value}, true
+}
+
+// fieldByKey returns the field named by key among fields.
+func fieldByKey(fields []*CustomField, key string) *CustomField {
+	for _, field := range fields {
+		if field.Key == key {
+			return field
+		}
+	}
+	return nil
+}
+
+// where adds the filter's condition to q. The filter must have been
+// validated against the project's fields.
+func (f FieldFilter) where(q *queryBuilder, field *CustomField) {
+	if f.Op == FieldOpEq {
+		var value any = f.Value
+		switch field.Type {
+		case FieldNumber, FieldUser:
+			value, _ = strconv.ParseFloat(f.Value, 64)
+		case FieldMultiSelect:
+			value = []string{f.Value}
+		}
+		// containment can use the GIN index on custom_fields
+		q.where("t.custom_fields @> " + q.arg(map[string]any{field.Key: value}) + "::jsonb")
+		return
+	}
+
+	op := ">="
+	if f.Op == FieldOpLte {
+		op = "<="
+	}
+
+	if field.Type == FieldNumber {
+		number, _ := strconv.ParseFloat(f.Value, 64)
+		q.where(field.value("t") + " " + op + " " + q.arg(number) + "::numeric")
+		return
+	}
+
+	// dates are stored as YYYY-MM-DD, which sorts as text
+	q.where(field.value("t") + " " + op + " " + q.arg(f.Value) + "::text")
+}
+
+// ValidateFieldFilter checks a filter against the fields of the project the
+// tasks are listed from.
+func ValidateFieldFilter(v *validator.Validator, f FieldFilter, fields []*CustomField) {
+	key := customFieldKeyPrefix + f.Key
+
+	field := fieldByKey(fields, f.Key)
+	if field == nil {
+		v.AddError(key, "is not a custom field of the project")
+		return
+	}
+
+	switch f.Op {
+	case FieldOpEq:
+	case FieldOpGte, FieldOpLte:
+		if field.Type != FieldNumber && field.Type != FieldDate {
+			v.AddError(key, "range filters only apply to number and date fields")
+			return
+		}
+	default:
+		v.AddError(key, "the operator must be one of `gte`, `lte`")
+		return
+	}
+
+	switch field.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(f.Value, 64)
+		v.Check(err == nil && !math.IsInf(n, 0) && !math.IsNaN(n), key, "must be a number")
+	case FieldUser:
+		id, err := strconv.Atoi(f.Value)
+		v.Check(err == nil && id > 0, key, "must be a user id")
+	case FieldDate:
+		_, err := time.Parse(time.DateOnly, f.Value)
+		v.Check(err == nil, key, "must be a YYYY-MM-DD date")
+	}
+}
+
+func ValidateCustomField(v *validator.Validator, field *CustomField) {
+	v.Check(validator.Matches(field.Key, fieldKeyRX), "key", "must be lower case like story_points, at most 32 characters long")
+	v.Check(validator.NotEmpty(field.Name), "name", "must be provided")
+	v.Check(len(field.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(validator.PremittedValues(field.Type, customFieldTypes), "type", "must be one of `text`, `number`, `date`, `single_select`, `multi_select`, `url`, `user`")
+
+	if field.Type == FieldSingleSelect || field.Type == FieldMultiSelect {
+		v.Check(len(field.Options) > 0, "options", "must not be empty for a select field")
+		v.Check(len(field.Options) <= maxFieldOptions, "options", fmt.Sprintf("must not have more than %d options", maxFieldOptions))
+		for i, option := range field.Options {
+			v.Check(validator.NotEmpty(option), "options", "must not contain empty options")
+			v.Check(len(option) <= 100, "options", "must not be more than 100 bytes long each")
+			v.Check(!slices.Contains(field.Options[:i], option), "options", "must be unique")
+		}
+	} else {
+		v.Check(len(field.Options) == 0, "options", "only apply to select fields")
+	}
+
+	if field.ProjectID < 1 {
+		panic("invalid operation,custom field can't exist without a project")
+	}
+}
+
+// ValidateCustomFieldValues checks values given for the fields of a task's
+// project, with an error key per field, and converts them to their stored
+// form. A nil value clears the field. User fields take the ids of project
+// members.
+func ValidateCustomFieldValues(v *validator.Validator, fields []*CustomField, values map[string]any, members []int) {
+	for key, value := range values {
+		errKey := "custom_fields." + key
+
+		field := fieldByKey(fields, key)
+		if field == nil {
+			v.AddError(errKey, "is not a custom field of the project")
+			continue
+		}
+		if value == nil {
+			continue
+		}
+
+		switch field.Type {
+		case FieldText:
+			s, ok := value.(string)
+			v.Check(ok && len(s) <= maxFieldValueLength, errKey, fmt.Sprintf("must be a string of at most %d bytes", maxFieldValueLength))
+
+		case FieldNumber:
+			n, ok := value.(float64)
+			v.Check(ok && !math.IsInf(n, 0) && !math.IsNaN(n), errKey, "must be a number")
+
+		case FieldDate:
+			s, ok := value.(string)
+			if ok {
+				_, err := time.Parse(time.DateOnly, s)
+				ok = err == nil
+			}
+			v.Check(ok, errKey, "must be a YYYY-MM-DD date")
+
+		case FieldSingleSelect:
+			s, ok := value.(string)
+			v.Check(ok && slices.Contains(field.Options, s), errKey, "must be one of the field's options")
+
+		case FieldMultiSelect:
+			items, ok := value.([]any)
+			selected := make([]string, 0, len(items))
+			for _, item := range items {
+				s, isString := item.(string)
+				ok = ok && isString && slices.Contains(field.Options, s)
+				if !slices.Contains(selected, s) {
+					selected = append(selected, s)
+				}
+			}
+			if v.Check(ok, errKey, "must be a list of the field's options"); ok {
+				values[key] = selected
+			}
+
+		case FieldURL:
+			s, ok := value.(string)
+			if ok {
+				u, err := url.ParseRequestURI(s)
+				ok = err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && len(s) <= maxFieldValueLength
+			}
+			v.Check(ok, errKey, "must be an http or https URL")
+
+		case FieldUser:
+			n, ok := value.(float64)
+			v.Check(ok && n == math.Trunc(n) && slices.Contains(members, int(n)), errKey, "must be the id of a project member")
+		}
+	}
+}
+
+// ApplyCustomFieldValues merges validated values into a task's custom fields.
+func (task *Task) ApplyCustomFieldValues(values map[string]any) {
+	if task.CustomFields == nil {
+		task.CustomFields = map[string]any{}
+	}
+
+	for key, value := range values {
+		if value == nil {
+			delete(task.CustomFields, key)
+		} else {
+			task.CustomFields[key] = value
+		}
+	}
+}
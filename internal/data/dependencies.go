@@ -0,0 +1,260 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrBlockerNotFound = errors.New("blocking task not found")
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+)
+
+// BlockedError is returned when a task is started or finished while some of
+// the tasks blocking it are unfinished.
+type BlockedError struct {
+	BlockerIDs []int
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("task is blocked by unfinished tasks %v", e.BlockerIDs)
+}
+
+type DependencyEdge struct {
+	TaskID      int `json:"task_id"`
+	BlockedByID int `json:"blocked_by_id"`
+}
+
+type DependencyNode struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Status    TaskStatus `json:"status"`
+	ProjectID *int       `json:"project_id"`
+	IsDone    bool       `json:"is_done"`
+}
+
+// DependencyGraph holds the tasks that block or are blocked by others. Order
+// lists them so that every task comes after all of its blockers.
+type DependencyGraph struct {
+	Nodes []*DependencyNode `json:"nodes"`
+	Edges []DependencyEdge  `json:"edges"`
+	Order []int             `json:"order"`
+}
+
+// needsUnblocked reports whether moving a task to status requires all of its
+// blockers to be finished, which is the case for any state past the
+// workflow's initial one.
+func (wf *Workflow) needsUnblocked(status TaskStatus) bool {
+	return status != wf.InitialStatus()
+}
+
+// checkUnblocked returns a *BlockedError if any task blocking taskID is
+// unfinished.
+func checkUnblocked(db dbtx, taskID int) error {
+	stmt := `
+SELECT b.id
+FROM task_dependencies d
+INNER JOIN tasks b ON b.id = d.blocked_by_id
+WHERE d.task_id = $1 AND b.deleted_at IS NULL AND NOT ` + taskIsDone("b") + `
+ORDER BY b.id`
+
+	rows, err := db.Query(context.Background(), stmt, taskID)
+	if err != nil {
+		return err
+	}
+
+	blockers, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	if len(blockers) > 0 {
+		return &BlockedError{BlockerIDs: blockers}
+	}
+
+	return nil
+}
+
+// GetBlockers returns the tasks blocking id that userID can read.
+func (t *tasksModel) GetBlockers(id, userID int) ([]*Task, error) {
+	stmt := `
+SELECT ` + taskColumns("$2") + `
+FROM task_dependencies d
+INNER JOIN tasks t ON t.id = d.blocked_by_id
+WHERE d.task_id = $1 AND ` + taskReadableBy("t", "$2") + `
+ORDER BY t.id`
+
+	rows, err := t.DB.Query(context.Background(), stmt, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []*Task{}
+
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(task.scanFields()...); err != nil {
+			return nil, err
+		}
+		task.afterScan()
+
+		tasks = append(tasks, &task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// AddBlocker marks id as blocked by blockerID, which userID must be able to
+// read. Edges that would close a cycle are rejected with ErrDependencyCycle.
+func (t *tasksModel) AddBlocker(id, blockerID, userID int) error {
+	if id == blockerID {
+		return ErrDependencyCycle
+	}
+
+	return pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		// serialize changes to the graph, two concurrent edges could
+		// otherwise close a cycle that neither of them sees
+		_, err := tx.Exec(context.Background(), `SELECT pg_advisory_xact_lock(hashtext('task_dependencies'))`)
+		if err != nil {
+			return err
+		}
+
+		var exists bool
+		err = tx.QueryRow(context.Background(), `SELECT EXISTS (SELECT 1 FROM tasks t WHERE t.id = $1 AND `+taskReadableBy("t", "$2")+`)`, blockerID, userID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrBlockerNotFound
+		}
+
+		// the edge closes a cycle if the blocker already depends on id
+		stmt := `
+WITH RECURSIVE upstream AS (
+  SELECT blocked_by_id AS id FROM task_dependencies WHERE task_id = $1
+  UNION
+  SELECT d.blocked_by_id FROM task_dependencies d INNER JOIN upstream u ON d.task_id = u.id
+)
+SELECT EXISTS (SELECT 1 FROM upstream WHERE id = $2)`
+
+		var cycle bool
+		err = tx.QueryRow(context.Background(), stmt, blockerID, id).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		_, err = tx.Exec(context.Background(), `INSERT INTO task_dependencies (task_id, blocked_by_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, blockerID)
+		return err
+	})
+}
+
+func (t *tasksModel) RemoveBlocker(id, blockerID int) error {
+	res, err := t.DB.Exec(context.Background(), `DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_id = $2`, id, blockerID)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() != 1 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DependencyGraph returns the dependencies between the tasks userID can read,
+// limited to the edges touching projectID when it is set.
+func (t *tasksModel) DependencyGraph(userID int, projectID *int) (*DependencyGraph, error) {
+	stmt := `
+SELECT d.task_id, d.blocked_by_id
+FROM task_dependencies d
+INNER JOIN tasks t ON t.id = d.task_id
+INNER JOIN tasks b ON b.id = d.blocked_by_id
+WHERE ` + taskReadableBy("t", "$1") + ` AND ` + taskReadableBy("b", "$1") + `
+  AND ($2::int IS NULL OR t.project_id = $2 OR b.project_id = $2)
+ORDER BY d.task_id, d.blocked_by_id`
+
+	rows, err := t.DB.Query(context.Background(), stmt, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	edges, err := pgx.CollectRows(rows, pgx.RowToStructByPos[DependencyEdge])
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, edge := range edges {
+		ids = append(ids, edge.TaskID, edge.BlockedByID)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	stmt = `SELECT t.id, t.title, t.status, t.project_id, ` + taskIsDone("t") + ` FROM tasks t WHERE t.id = ANY($1) ORDER BY t.id`
+
+	rows, err = t.DB.Query(context.Background(), stmt, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[DependencyNode])
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := topologicalOrder(ids, edges)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DependencyGraph{Nodes: nodes, Edges: edges, Order: order}, nil
+}
+
+// topologicalOrder sorts ids so that blockers come before the tasks they
+// block, breaking ties by id to keep the order stable.
+func topologicalOrder(ids []int, edges []DependencyEdge) ([]int, error) {
+	blockers := make(map[int]int, len(ids))
+	blocks := make(map[int][]int, len(ids))
+
+	for _, edge := range edges {
+		blockers[edge.TaskID]++
+		blocks[edge.BlockedByID] = append(blocks[edge.BlockedByID], edge.TaskID)
+	}
+
+	var ready []int
+	for _, id := range ids {
+		if blockers[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	order := make([]int, 0, len(ids))
+
+	for len(ready) > 0 {
+		slices.Sort(ready)
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+
+		for _, blocked := range blocks[id] {
+			blockers[blocked]--
+			if blockers[blocked] == 0 {
+				ready = append(ready, blocked)
+			}
+		}
+	}
+
+	if len(order) != len(ids) {
+		return nil, ErrDependencyCycle
+	}
+
+	return order, nil
+}
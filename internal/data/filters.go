@@ -0,0 +1,88 @@
+package data
+
+import (
+	"math"
+	"strings"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+type Filters struct {
+	Page     int
+	PageSize int
+	Sort     []string
+}
+
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records"`
+}
+
+func (f Filters) limit() int {
+	return f.PageSize
+}
+
+func (f Filters) offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+// orderBy turns the sort keys into an ORDER BY list using the given allow-list
+// of column expressions. A trailing id sort keeps the ordering stable between
+// pages. Keys must have been checked with ValidateFilters first.
+func (f Filters) orderBy(columns map[string]string, idColumn string) string {
+	clauses := make([]string, 0, len(f.Sort)+1)
+	hasID := false
+
+	for _, key := range f.Sort {
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			direction = "DESC"
+		}
+
+		name := strings.TrimPrefix(key, "-")
+		column, ok := columns[name]
+		if !ok {
+			panic("unsafe sort parameter: " + key)
+		}
+		if column == idColumn {
+			hasID = true
+		}
+
+		clauses = append(clauses, column+" "+direction)
+	}
+
+	if !hasID {
+		clauses = append(clauses, idColumn+" ASC")
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
+
+func ValidateFilters(v *validator.Validator, f Filters, sortColumns map[string]string) {
+	v.Check(f.Page > 0, "page", "must be greater than zero")
+	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+
+	for _, key := range f.Sort {
+		_, ok := sortColumns[strings.TrimPrefix(key, "-")]
+		v.Check(ok, "sort", "invalid sort value")
+	}
+}
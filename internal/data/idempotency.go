@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+// StoredResponse is the response to a request sent with an idempotency key,
+// replayed when the request is retried.
+type StoredResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type idempotencyKeysModel struct {
+	DB *pgxpool.Pool
+}
+
+// Reserve claims key for a request of userID hashing to requestHash. Keys
+// older than ttl are free again. It returns nil when the caller got the key
+// and must Save or Release it, or the stored response when the request was
+// already answered. A key used for another request fails with
+// ErrIdempotencyKeyReused, one whose request is still running with
+// ErrIdempotencyKeyInProgress.
+func (ik idempotencyKeysModel) Reserve(userID int, key string, requestHash []byte, ttl time.Duration) (*StoredResponse, error) {
+	stmt := `
+INSERT INTO idempotency_keys AS k (user_id, key, request_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash, status = NULL, headers = NULL, body = NULL, created_at = now()
+WHERE k.created_at < $4
+RETURNING true`
+
+	var reserved bool
+
+	err := ik.DB.QueryRow(context.Background(), stmt, userID, key, requestHash, time.Now().Add(-ttl)).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	var storedHash []byte
+	var status *int
+	var response StoredResponse
+
+	stmt = `SELECT request_hash, status, headers, body FROM idempotency_keys WHERE user_id = $1 AND key = $2`
+
+	err = ik.DB.QueryRow(context.Background(), stmt, userID, key).Scan(&storedHash, &status, &response.Header, &response.Body)
+	if err != nil {
+		// the key expired and was purged in between
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ik.Reserve(userID, key, requestHash, ttl)
+		}
+		return nil, err
+	}
+
+	switch {
+	case string(storedHash) != string(requestHash):
+		return nil, ErrIdempotencyKeyReused
+	case status == nil:
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	response.Status = *status
+	return &response, nil
+}
+
+// Save stores the response to the request that reserved key.
+func (ik idempotencyKeysModel) Save(userID int, key string, response *StoredResponse) error {
+	stmt := `UPDATE idempotency_keys SET status = $3, headers = $4, body = $5 WHERE user_id = $1 AND key = $2`
+
+	_, err := ik.DB.Exec(context.Background(), stmt, userID, key, response.Status, response.Header, response.Body)
+	return err
+}
+
+// Release frees a key whose request didn't get a response worth replaying,
+// so the request can be retried.
+func (ik idempotencyKeysModel) Release(userID int, key string) error {
+	_, err := ik.DB.Exec(context.Background(), `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status IS NULL`, userID, key)
+	return err
+}
+
+// DeleteExpired removes up to limit keys older than ttl and returns how many
+// it removed. Rows locked by another instance are skipped.
+func (ik idempotencyKeysModel) DeleteExpired(ttl time.Duration, limit int) (int, error) {
+	stmt := `
+DELETE FROM idempotency_keys
+WHERE (user_id, key) IN (
+  SELECT user_id, key FROM idempotency_keys WHERE created_at < $1 LIMIT $2 FOR UPDATE SKIP LOCKED
+)`
+
+	res, err := ik.DB.Exec(context.Background(), stmt, time.Now().Add(-ttl), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
@@ -0,0 +1,267 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+var ErrDuplicateLabel = errors.New("duplicate label")
+
+const (
+	LabelMatchAny = "any"
+	LabelMatchAll = "all"
+)
+
+const defaultLabelColor = "#808080"
+
+type Label struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	TaskCount int       `json:"task_count"`
+	UserID    int       `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type labelsModel struct {
+	DB *pgxpool.Pool
+}
+
+func (l labelsModel) GetAll(userID int) ([]*Label, error) {
+	stmt := `
+SELECT l.id, l.name, l.color, (SELECT count(*) FROM task_labels tl INNER JOIN tasks t ON t.id = tl.task_id WHERE tl.label_id = l.id AND t.deleted_at IS NULL), l.user_id, l.created_at
+FROM labels l
+WHERE l.user_id = $1
+ORDER BY l.name`
+
+	rows, err := l.DB.Query(context.Background(), stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := []*Label{}
+
+	for rows.Next() {
+		var label Label
+		err := rows.Scan(&label.ID, &label.Name, &label.Color, &label.TaskCount, &label.UserID, &label.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		labels = append(labels, &label)
+	}
+
+	return labels, rows.Err()
+}
+
+func (l labelsModel) Get(id, userID int) (*Label, error) {
+	stmt := `
+SELECT l.id, l.name, l.color, (SELECT count(*) FROM task_labels tl INNER JOIN tasks t ON t.id = tl.task_id WHERE tl.label_id = l.id AND t.deleted_at IS NULL), l.user_id, l.created_at
+FROM labels l
+WHERE l.id = $1 AND l.user_id = $2`
+
+	var label Label
+	err := l.DB.QueryRow(context.Background(), stmt, id, userID).Scan(&label.ID, &label.Name, &label.Color, &label.TaskCount, &label.UserID, &label.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &label, nil
+}
+
+func (l labelsModel) Insert(label *Label) error {
+	stmt := `
+INSERT INTO labels (name, color, user_id)
+VALUES ($1, $2, $3)
+RETURNING id, created_at`
+
+	err := l.DB.QueryRow(context.Background(), stmt, label.Name, label.Color, label.UserID).Scan(&label.ID, &label.CreatedAt)
+	if err != nil {
+		if isDuplicateLabel(err) {
+			return ErrDuplicateLabel
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (l labelsModel) Update(label *Label) error {
+	stmt := `UPDATE labels SET name = $1, color = $2 WHERE id = $3 AND user_id = $4`
+
+	res, err := l.DB.Exec(context.Background(), stmt, label.Name, label.Color, label.ID, label.UserID)
+	if err != nil {
+		if isDuplicateLabel(err) {
+			return ErrDuplicateLabel
+		}
+		return err
+	}
+
+	if res.RowsAffected() != 1 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (l labelsModel) Delete(id, userID int) error {
+	res, err := l.DB.Exec(context.Background(), `DELETE FROM labels WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() != 1 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Attach adds the named labels to a task, creating the ones the user doesn't
+// have yet. Labels already on the task are left alone. Both bump the task's
+// version, as the task shows its labels.
+func (l labelsModel) Attach(taskID, userID int, names []string) error {
+	return pgx.BeginFunc(context.Background(), l.DB, func(tx pgx.Tx) error {
+		ids, err := ensureLabels(tx, userID, names)
+		if err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO task_labels (task_id, label_id) SELECT $1, unnest($2::integer[]) ON CONFLICT DO NOTHING`
+		res, err := tx.Exec(context.Background(), stmt, taskID, ids)
+		if err != nil || res.RowsAffected() == 0 {
+			return err
+		}
+
+		return bumpTaskVersion(tx, taskID)
+	})
+}
+
+func (l labelsModel) Detach(taskID, labelID, userID int) error {
+	return pgx.BeginFunc(context.Background(), l.DB, func(tx pgx.Tx) error {
+		stmt := `
+DELETE FROM task_labels tl
+USING labels l
+WHERE tl.label_id = l.id AND tl.task_id = $1 AND tl.label_id = $2 AND l.user_id = $3`
+
+		res, err := tx.Exec(context.Background(), stmt, taskID, labelID, userID)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() != 1 {
+			return ErrRecordNotFound
+		}
+
+		return bumpTaskVersion(tx, taskID)
+	})
+}
+
+// bumpTaskVersion marks a task as changed by something outside its row.
+func bumpTaskVersion(db dbtx, taskID int) error {
+	_, err := db.Exec(context.Background(), `UPDATE tasks SET version = version + 1 WHERE id = $1`, taskID)
+	return err
+}
+
+// ensureLabels returns the ids of the user's labels with the given names,
+// creating the missing ones with the default colour.
+func ensureLabels(db dbtx, userID int, names []string) ([]int, error) {
+	names = NormalizeLabelNames(names)
+	if len(names) == 0 {
+		return []int{}, nil
+	}
+
+	stmt := `
+INSERT INTO labels (name, color, user_id)
+SELECT unnest($1::text[]), $2, $3
+ON CONFLICT (user_id, name) DO NOTHING`
+
+	_, err := db.Exec(context.Background(), stmt, names, defaultLabelColor, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+
+	rows, err := db.Query(context.Background(), `SELECT id FROM labels WHERE user_id = $1 AND lower(name::text) = ANY($2::text[])`, userID, lowered)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+// setTaskLabels makes the user's labels on the task exactly the named ones.
+func setTaskLabels(db dbtx, taskID, userID int, names []string) error {
+	ids, err := ensureLabels(db, userID, names)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+DELETE FROM task_labels
+WHERE task_id = $1 AND label_id <> ALL($2::integer[]) AND label_id IN (SELECT id FROM labels WHERE user_id = $3)`
+
+	_, err = db.Exec(context.Background(), stmt, taskID, ids, userID)
+	if err != nil {
+		return err
+	}
+
+	stmt = `INSERT INTO task_labels (task_id, label_id) SELECT $1, unnest($2::integer[]) ON CONFLICT DO NOTHING`
+	_, err = db.Exec(context.Background(), stmt, taskID, ids)
+	return err
+}
+
+// NormalizeLabelNames trims the names and drops blanks and case-insensitive
+// duplicates, keeping the first spelling.
+func NormalizeLabelNames(names []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		normalized = append(normalized, name)
+	}
+
+	return normalized
+}
+
+func isDuplicateLabel(err error) bool {
+	var pgError *pgconn.PgError
+	return errors.As(err, &pgError) && pgError.Code == pgerrcode.UniqueViolation && strings.Contains(pgError.ConstraintName, "labels_user_id_name")
+}
+
+func ValidateLabelName(v *validator.Validator, key, name string) {
+	v.Check(validator.NotEmpty(name), key, "must be provided")
+	v.Check(len(name) <= 50, key, "must not be more than 50 bytes long")
+	v.Check(!strings.Contains(name, ","), key, "must not contain commas")
+}
+
+func ValidateLabel(v *validator.Validator, label *Label) {
+	ValidateLabelName(v, "name", label.Name)
+	v.Check(validator.Matches(label.Color, validator.ColorRX), "color", "must be a hex colour like #1e90ff")
+	if label.UserID < 1 {
+		panic("invalid operation,label can't exist without a user")
+	}
+}
@@ -1,11 +1,37 @@
 package data
 
-import "github.com/jackc/pgx/v5/pgxpool"
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// dbtx is implemented by both *pgxpool.Pool and pgx.Tx, so queries can run
+// either directly on the pool or inside a transaction.
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
 
 type Models struct {
-	Tasks  tasksModel
-	Users  usersModel
-	Tokens tokensModel
+	Tasks           tasksModel
+	Users           usersModel
+	Tokens          tokensModel
+	Labels          labelsModel
+	Projects        projectsModel
+	Members         projectMembersModel
+	Comments        commentsModel
+	Attachments     attachmentsModel
+	Reminders       remindersModel
+	Checklists      checklistsModel
+	Workflows       workflowsModel
+	CustomFields    customFieldsModel
+	TaskEvents      taskEventsModel
+	IdempotencyKeys idempotencyKeysModel
 }
 
 func NewModels(db *pgxpool.Pool) Models {
@@ -19,5 +45,38 @@ func NewModels(db *pgxpool.Pool) Models {
 		Tokens: tokensModel{
 			DB: db,
 		},
+		Labels: labelsModel{
+			DB: db,
+		},
+		Projects: projectsModel{
+			DB: db,
+		},
+		Members: projectMembersModel{
+			DB: db,
+		},
+		Comments: commentsModel{
+			DB: db,
+		},
+		Attachments: attachmentsModel{
+			DB: db,
+		},
+		Reminders: remindersModel{
+			DB: db,
+		},
+		Checklists: checklistsModel{
+			DB: db,
+		},
+		Workflows: workflowsModel{
+			DB: db,
+		},
+		CustomFields: customFieldsModel{
+			DB: db,
+		},
+		TaskEvents: taskEventsModel{
+			DB: db,
+		},
+		IdempotencyKeys: idempotencyKeysModel{
+			DB: db,
+		},
 	}
 }
@@ -0,0 +1,252 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+const ScopeInvitation = "invitation"
+
+var (
+	ErrLastOwner         = errors.New("project must keep at least one owner")
+	ErrInvitationEmail   = errors.New("invitation was sent to a different email")
+	ErrAlreadyMember     = errors.New("user is already a member of the project")
+	ErrInvitationExpired = errors.New("invitation not found or expired")
+)
+
+type ProjectMember struct {
+	UserID    int         `json:"user_id"`
+	Name      string      `json:"name"`
+	Email     string      `json:"email"`
+	Role      ProjectRole `json:"role"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type ProjectInvitation struct {
+	ID        int         `json:"id"`
+	ProjectID int         `json:"project_id"`
+	Email     string      `json:"email"`
+	Role      ProjectRole `json:"role"`
+	InvitedBy int         `json:"invited_by"`
+	Expiry    time.Time   `json:"expiry"`
+	CreatedAt time.Time   `json:"created_at"`
+	Token     string      `json:"-"`
+}
+
+type projectMembersModel struct {
+	DB *pgxpool.Pool
+}
+
+func (m projectMembersModel) GetAll(projectID int) ([]*ProjectMember, error) {
+	stmt := `
+SELECT u.id, u.name, u.email, pm.role, pm.created_at
+FROM project_members pm
+INNER JOIN users u ON u.id = pm.user_id
+WHERE pm.project_id = $1
+ORDER BY pm.created_at, u.id`
+
+	rows, err := m.DB.Query(context.Background(), stmt, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*ProjectMember{}
+
+	for rows.Next() {
+		var member ProjectMember
+		if err := rows.Scan(&member.UserID, &member.Name, &member.Email, &member.Role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		members = append(members, &member)
+	}
+
+	return members, rows.Err()
+}
+
+// UpdateRole changes a member's role, refusing to demote the last owner.
+func (m projectMembersModel) UpdateRole(projectID, userID int, role ProjectRole) error {
+	return pgx.BeginFunc(context.Background(), m.DB, func(tx pgx.Tx) error {
+		res, err := tx.Exec(context.Background(), `UPDATE project_members SET role = $1 WHERE project_id = $2 AND user_id = $3`, role, projectID, userID)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() != 1 {
+			return ErrRecordNotFound
+		}
+
+		return checkOwners(tx, projectID)
+	})
+}
+
+// Delete removes a member, refusing to remove the last owner. The tasks
+// assigned to them in the project are unassigned.
+func (m projectMembersModel) Delete(projectID, userID int) error {
+	return pgx.BeginFunc(context.Background(), m.DB, func(tx pgx.Tx) error {
+		res, err := tx.Exec(context.Background(), `DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`, projectID, userID)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() != 1 {
+			return ErrRecordNotFound
+		}
+
+		_, err = tx.Exec(context.Background(), `UPDATE tasks SET assignee_id = NULL, version = version + 1 WHERE project_id = $1 AND assignee_id = $2`, projectID, userID)
+		if err != nil {
+			return err
+		}
+
+		return checkOwners(tx, projectID)
+	})
+}
+
+func checkOwners(db dbtx, projectID int) error {
+	// lock the project row so concurrent demotions can't both see another owner
+	_, err := db.Exec(context.Background(), `SELECT 1 FROM projects WHERE id = $1 FOR UPDATE`, projectID)
+	if err != nil {
+		return err
+	}
+
+	var owners int
+	err = db.QueryRow(context.Background(), `SELECT count(*) FROM project_members WHERE project_id = $1 AND role = 'owner'`, projectID).Scan(&owners)
+	if err != nil {
+		return err
+	}
+
+	if owners == 0 {
+		return ErrLastOwner
+	}
+
+	return nil
+}
+
+func (m projectMembersModel) GetInvitations(projectID int) ([]*ProjectInvitation, error) {
+	stmt := `
+SELECT id, project_id, email, role, invited_by, expiry, created_at
+FROM project_invitations
+WHERE project_id = $1 AND expiry > now()
+ORDER BY created_at, id`
+
+	rows, err := m.DB.Query(context.Background(), stmt, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*ProjectInvitation{}
+
+	for rows.Next() {
+		var invitation ProjectInvitation
+		err := rows.Scan(&invitation.ID, &invitation.ProjectID, &invitation.Email, &invitation.Role, &invitation.InvitedBy, &invitation.Expiry, &invitation.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, &invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// Invite stores a new invitation and sets its plain text token, which is
+// only ever sent to the invitee.
+func (m projectMembersModel) Invite(invitation *ProjectInvitation, ttl time.Duration) error {
+	token, err := generateToken(invitation.InvitedBy, ttl, ScopeInvitation)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+INSERT INTO project_invitations (project_id, email, role, hash, invited_by, expiry)
+SELECT $1, $2, $3, $4, $5, $6
+WHERE NOT EXISTS (
+  SELECT 1 FROM project_members pm INNER JOIN users u ON u.id = pm.user_id
+  WHERE pm.project_id = $1 AND u.email = $2
+)
+RETURNING id, created_at`
+
+	args := []any{invitation.ProjectID, invitation.Email, invitation.Role, token.Hash, invitation.InvitedBy, token.Expiry}
+
+	err = m.DB.QueryRow(context.Background(), stmt, args...).Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAlreadyMember
+		}
+		return err
+	}
+
+	invitation.Token = token.PlainText
+	invitation.Expiry = token.Expiry
+
+	return nil
+}
+
+func (m projectMembersModel) DeleteInvitation(id, projectID int) error {
+	res, err := m.DB.Exec(context.Background(), `DELETE FROM project_invitations WHERE id = $1 AND project_id = $2`, id, projectID)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() != 1 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Accept turns the invitation matching token into a membership for user,
+// whose email must be the one the invitation was sent to.
+func (m projectMembersModel) Accept(token string, user *User) (int, error) {
+	hash := sha256.Sum256([]byte(token))
+
+	var projectID int
+
+	err := pgx.BeginFunc(context.Background(), m.DB, func(tx pgx.Tx) error {
+		stmt := `
+DELETE FROM project_invitations
+WHERE hash = $1 AND expiry > now()
+RETURNING project_id, email, role`
+
+		var email string
+		var role ProjectRole
+
+		err := tx.QueryRow(context.Background(), stmt, hash[:]).Scan(&projectID, &email, &role)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrInvitationExpired
+			}
+			return err
+		}
+
+		var matches bool
+		err = tx.QueryRow(context.Background(), `SELECT $1::citext = $2::citext`, email, user.Email).Scan(&matches)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			return ErrInvitationEmail
+		}
+
+		stmt = `
+INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3)
+ON CONFLICT (project_id, user_id) DO NOTHING`
+
+		_, err = tx.Exec(context.Background(), stmt, projectID, user.ID, role)
+		return err
+	})
+
+	return projectID, err
+}
+
+func ValidateProjectRole(v *validator.Validator, role ProjectRole) {
+	v.Check(validator.PremittedValues(role, []ProjectRole{RoleOwner, RoleEditor, RoleViewer}), "role", "must be one of `owner`, `editor`, `viewer`")
+}
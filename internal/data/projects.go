@@ -0,0 +1,321 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+var ErrProjectNotFound = errors.New("project not found")
+
+// What happens to a project's tasks when the project is deleted.
+const (
+	ProjectDeleteCascade = "cascade"
+	ProjectDeleteInbox   = "inbox"
+)
+
+type Project struct {
+	ID          int                `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Color       string             `json:"color"`
+	Archived    bool               `json:"archived"`
+	Role        ProjectRole        `json:"role"`
+	TaskCounts  map[TaskStatus]int `json:"task_counts"`
+	UserID      int                `json:"user_id"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type projectsModel struct {
+	DB *pgxpool.Pool
+}
+
+// projectColumns selects a project together with the role of the member
+// joined as pm.
+const projectColumns = `p.id, p.name, p.description, p.color, p.archived, pm.role,
+COALESCE((SELECT json_object_agg(s.status, s.count) FROM (SELECT status, count(*) FROM tasks WHERE project_id = p.id AND deleted_at IS NULL GROUP BY status) s), '{}'),
+p.user_id, p.created_at`
+
+func (project *Project) scanFields() []any {
+	return []any{&project.ID, &project.Name, &project.Description, &project.Color, &project.Archived, &project.Role, &project.TaskCounts, &project.UserID, &project.CreatedAt}
+}
+
+// GetAll lists the projects the user is a member of. A nil archived returns
+// both archived and active projects.
+func (p projectsModel) GetAll(userID int, archived *bool) ([]*Project, error) {
+	stmt := `
+SELECT ` + projectColumns + `
+FROM projects p
+INNER JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $1
+WHERE $2::boolean IS NULL OR p.archived = $2
+ORDER BY p.name, p.id`
+
+	rows, err := p.DB.Query(context.Background(), stmt, userID, archived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []*Project{}
+
+	for rows.Next() {
+		var project Project
+		if err := rows.Scan(project.scanFields()...); err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, &project)
+	}
+
+	return projects, rows.Err()
+}
+
+func (p projectsModel) Get(id, userID int) (*Project, error) {
+	stmt := `
+SELECT ` + projectColumns + `
+FROM projects p
+INNER JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $2
+WHERE p.id = $1`
+
+	var project Project
+	err := p.DB.QueryRow(context.Background(), stmt, id, userID).Scan(project.scanFields()...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// Insert creates the project and makes project.UserID its owner.
+func (p projectsModel) Insert(project *Project) error {
+	stmt := `
+INSERT INTO projects (name, description, color, archived, user_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at`
+
+	args := []any{project.Name, project.Description, project.Color, project.Archived, project.UserID}
+
+	return pgx.BeginFunc(context.Background(), p.DB, func(tx pgx.Tx) error {
+		err := tx.QueryRow(context.Background(), stmt, args...).Scan(&project.ID, &project.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(context.Background(), `INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3)`, project.ID, project.UserID, RoleOwner)
+		if err != nil {
+			return err
+		}
+
+		project.Role = RoleOwner
+		project.TaskCounts = map[TaskStatus]int{}
+		return nil
+	})
+}
+
+// Update saves the project on behalf of userID, who must be one of its
+// owners.
+func (p projectsModel) Update(project *Project, userID int) error {
+	stmt := `
+UPDATE projects p SET name = $1, description = $2, color = $3, archived = $4
+WHERE p.id = $5 AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = $6 AND pm.role = 'owner')`
+
+	args := []any{project.Name, project.Description, project.Color, project.Archived, project.ID, userID}
+
+	res, err := p.DB.Exec(context.Background(), stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() != 1 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a project on behalf of one of its owners. Its tasks go to
+// the trash when mode is ProjectDeleteCascade, where the purge job removes
+// them for good, otherwise they move back to the inbox of the users who
+// created them. Tasks restored from the trash come back to the inbox too.
+func (p projectsModel) Delete(id, userID int, mode string) error {
+	return pgx.BeginFunc(context.Background(), p.DB, func(tx pgx.Tx) error {
+		role, err := projectRole(tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if role != RoleOwner {
+			return ErrProjectReadOnly
+		}
+
+		if err := detachForeignSubtasks(tx, id, userID); err != nil {
+			return err
+		}
+
+		// the tasks moving to the inbox
+		var tasks []int
+
+		if mode == ProjectDeleteCascade {
+			// tasks already in the trash keep the time they were trashed at
+			rows, err := tx.Query(context.Background(), `UPDATE tasks SET deleted_at = now(), version = version + 1 WHERE project_id = $1 AND deleted_at IS NULL RETURNING id`, id)
+			if err != nil {
+				return err
+			}
+
+			trashed, err := pgx.CollectRows(rows, pgx.RowTo[int])
+			if err != nil {
+				return err
+			}
+
+			if err := recordTaskEvents(tx, trashed, userID, TaskDeleted, nil); err != nil {
+				return err
+			}
+		} else {
+			rows, err := tx.Query(context.Background(), `SELECT id FROM tasks WHERE project_id = $1`, id)
+			if err != nil {
+				return err
+			}
+
+			tasks, err = pgx.CollectRows(rows, pgx.RowTo[int])
+			if err != nil {
+				return err
+			}
+
+			if err := fitInboxStatuses(tx, id); err != nil {
+				return err
+			}
+			// the project's custom fields go with it
+			if _, err := tx.Exec(context.Background(), `UPDATE tasks SET custom_fields = '{}', version = version + 1 WHERE project_id = $1`, id); err != nil {
+				return err
+			}
+			if err := recordTaskEvents(tx, tasks, userID, TaskUpdated, map[string]FieldChange{"project_id": {From: id, To: nil}}); err != nil {
+				return err
+			}
+		}
+
+		// the foreign key moves the tasks to the inbox
+		if _, err := tx.Exec(context.Background(), `DELETE FROM projects WHERE id = $1`, id); err != nil {
+			return err
+		}
+
+		// other members can't see the tasks in the inbox and lose them
+		stmt := `UPDATE tasks t SET assignee_id = NULL, version = t.version + 1 WHERE t.id = ANY($1) AND t.assignee_id IS NOT NULL AND NOT ` + taskScope("t", "t.assignee_id")
+		_, err = tx.Exec(context.Background(), stmt, tasks)
+		return err
+	})
+}
+
+// detachForeignSubtasks makes the subtasks of a project's tasks top level
+// tasks when someone else created their parent. The tasks of a deleted
+// project end up in their creators' inboxes, and a subtask must stay on its
+// parent's board.
+func detachForeignSubtasks(tx pgx.Tx, projectID, userID int) error {
+	stmt := `
+UPDATE tasks c SET parent_id = NULL, version = c.version + 1
+FROM tasks p
+WHERE p.id = c.parent_id AND c.project_id = $1 AND c.user_id <> p.user_id
+RETURNING c.id, p.id`
+
+	rows, err := tx.Query(context.Background(), stmt, projectID)
+	if err != nil {
+		return err
+	}
+
+	type detached struct {
+		ID       int
+		ParentID int
+	}
+
+	subtasks, err := pgx.CollectRows(rows, pgx.RowToStructByPos[detached])
+	if err != nil {
+		return err
+	}
+
+	for _, subtask := range subtasks {
+		err := recordTaskEvents(tx, []int{subtask.ID}, userID, TaskUpdated, map[string]FieldChange{"parent_id": {From: subtask.ParentID, To: nil}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fitInboxStatuses makes the statuses of a project's tasks fit the workflows
+// of the inboxes they move back to.
+func fitInboxStatuses(tx pgx.Tx, projectID int) error {
+	rows, err := tx.Query(context.Background(), `SELECT user_id, array_agg(id) FROM tasks WHERE project_id = $1 GROUP BY user_id`, projectID)
+	if err != nil {
+		return err
+	}
+
+	type inbox struct {
+		UserID  int
+		TaskIDs []int
+	}
+
+	inboxes, err := pgx.CollectRows(rows, pgx.RowToStructByPos[inbox])
+	if err != nil {
+		return err
+	}
+
+	for _, inbox := range inboxes {
+		if err := lockBoard(tx, nil, inbox.UserID); err != nil {
+			return err
+		}
+
+		workflow, err := workflowFor(tx, nil, inbox.UserID)
+		if err != nil {
+			return err
+		}
+
+		if err := fitStatuses(tx, workflow, inbox.TaskIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkProject makes sure the user can add tasks to the project: they must be
+// an editor or owner, and archived projects don't take new tasks.
+func checkProject(db dbtx, projectID, userID int) error {
+	stmt := `
+SELECT pm.role, p.archived
+FROM projects p
+INNER JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $2
+WHERE p.id = $1`
+
+	var role ProjectRole
+	var archived bool
+
+	err := db.QueryRow(context.Background(), stmt, projectID, userID).Scan(&role, &archived)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProjectNotFound
+		}
+		return err
+	}
+
+	if !role.CanWrite() || archived {
+		return ErrProjectReadOnly
+	}
+
+	return nil
+}
+
+func ValidateProject(v *validator.Validator, project *Project) {
+	v.Check(validator.NotEmpty(project.Name), "name", "must be provided")
+	v.Check(len(project.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(project.Description) <= 2000, "description", "must not be more than 2000 bytes long")
+	v.Check(validator.Matches(project.Color, validator.ColorRX), "color", "must be a hex colour like #1e90ff")
+	if project.UserID < 1 {
+		panic("invalid operation,project can't exist without a user")
+	}
+}
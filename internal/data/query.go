@@ -0,0 +1,31 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+)
+
+// queryBuilder collects WHERE conditions and their positional arguments so
+// list queries can be assembled from optional filters.
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// arg registers a query argument and returns its placeholder.
+func (q *queryBuilder) arg(value any) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *queryBuilder) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+func (q *queryBuilder) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
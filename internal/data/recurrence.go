@@ -0,0 +1,123 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/moutafatin/go-tasks-management-api/internal/rrule"
+)
+
+const MaxOccurrencesPreview = 100
+
+// NormalizeRecurrence returns the canonical form of a recurrence rule, or nil
+// when it is empty. Invalid rules are returned as is for ValidateTask to
+// report.
+func NormalizeRecurrence(recurrence *string) *string {
+	if recurrence == nil || strings.TrimSpace(*recurrence) == "" {
+		return nil
+	}
+
+	rule, err := rrule.Parse(*recurrence)
+	if err != nil {
+		return recurrence
+	}
+
+	normalized := rule.String()
+	return &normalized
+}
+
+// Occurrences returns up to n due dates that follow the task's current one.
+func (task *Task) Occurrences(n int) ([]time.Time, error) {
+	if task.Recurrence == nil || task.DueAt == nil || task.RecurrenceStart == nil {
+		return []time.Time{}, nil
+	}
+
+	rule, err := rrule.Parse(*task.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := rule.Next(*task.RecurrenceStart, *task.DueAt, n)
+	if occurrences == nil {
+		occurrences = []time.Time{}
+	}
+
+	return occurrences, nil
+}
+
+// insertNextOccurrence copies a completed recurring task, with its labels,
+// assignee, custom fields, offset reminders and unchecked checklist, to the
+// next due date of its series. Each task spawns at most one occurrence, so
+// completing it again after reopening it is a no-op. The occurrence is
+// recorded as created by actorID, who completed the task.
+func (t *tasksModel) insertNextOccurrence(tx pgx.Tx, task *Task, actorID int) error {
+	occurrences, err := task.Occurrences(1)
+	if err != nil || len(occurrences) == 0 {
+		return err
+	}
+
+	dueAt := occurrences[0]
+
+	var startAt *time.Time
+	if task.StartAt != nil {
+		s := dueAt.Add(task.StartAt.Sub(*task.DueAt))
+		startAt = &s
+	}
+
+	err = lockBoard(tx, task.ProjectID, task.UserID)
+	if err != nil {
+		return err
+	}
+
+	workflow, err := workflowFor(tx, task.ProjectID, task.UserID)
+	if err != nil {
+		return err
+	}
+
+	position, err := nextPosition(tx, task.ProjectID, task.UserID, workflow.InitialStatus())
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+INSERT INTO tasks (title, description, priority, status, project_id, parent_id, assignee_id, start_at, due_at, recurrence, recurrence_start, recurrence_prev_id, position, custom_fields, user_id)
+SELECT title, description, priority, $2, project_id, parent_id, assignee_id, $3, $4, recurrence, recurrence_start, id, $5, custom_fields, user_id
+FROM tasks WHERE id = $1
+ON CONFLICT (recurrence_prev_id) DO NOTHING
+RETURNING id`
+
+	var id int
+	err = tx.QueryRow(context.Background(), stmt, task.ID, workflow.InitialStatus(), startAt, dueAt, position).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `INSERT INTO task_labels (task_id, label_id) SELECT $1, label_id FROM task_labels WHERE task_id = $2`, id, task.ID)
+	if err != nil {
+		return err
+	}
+
+	err = copyReminders(tx, task.ID, id)
+	if err != nil {
+		return err
+	}
+
+	err = copyChecklist(tx, task.ID, id)
+	if err != nil {
+		return err
+	}
+
+	err = recordTaskEvents(tx, []int{id}, actorID, TaskCreated, nil)
+	if err != nil {
+		return err
+	}
+
+	task.NextOccurrenceID = &id
+	return nil
+}
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// MaxReminderAttempts is how many times sending a reminder is tried before
+// it is given up on.
+const MaxReminderAttempts = 5
+
+// Reminder emails its user either at RemindAt or OffsetMinutes before the
+// task's due date. FireAt is the resulting time, nil for an offset on a task
+// without a due date.
+type Reminder struct {
+	ID            int        `json:"id"`
+	TaskID        int        `json:"task_id"`
+	UserID        int        `json:"user_id"`
+	RemindAt      *time.Time `json:"remind_at"`
+	OffsetMinutes *int       `json:"offset_minutes"`
+	FireAt        *time.Time `json:"fire_at"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// DueReminder is a reminder claimed for delivery, with what the email needs.
+type DueReminder struct {
+	Reminder
+	TaskTitle string
+	DueAt     *time.Time
+	Email     string
+	Name      string
+}
+
+type remindersModel struct {
+	DB *pgxpool.Pool
+}
+
+// reminderFireAt returns the SQL expression behind Reminder.FireAt.
+func reminderFireAt(reminder, task string) string {
+	return "COALESCE(" + reminder + ".remind_at, " + task + ".due_at - make_interval(mins => " + reminder + ".offset_minutes))"
+}
+
+var reminderColumns = `r.id, r.task_id, r.user_id, r.remind_at, r.offset_minutes, ` + reminderFireAt("r", "t") + `, r.sent_at, r.created_at`
+
+func (reminder *Reminder) scanFields() []any {
+	return []any{&reminder.ID, &reminder.TaskID, &reminder.UserID, &reminder.RemindAt, &reminder.OffsetMinutes, &reminder.FireAt, &reminder.SentAt, &reminder.CreatedAt}
+}
+
+// GetAllForTask lists the reminders userID set on a task. Reminders are
+// personal, other members' reminders on a shared task are not included.
+func (rm remindersModel) GetAllForTask(taskID, userID int) ([]*Reminder, error) {
+	stmt := `
+SELECT ` + reminderColumns + `
+FROM task_reminders r
+INNER JOIN tasks t ON t.id = r.task_id
+WHERE r.task_id = $1 AND r.user_id = $2
+ORDER BY ` + reminderFireAt("r", "t") + ` NULLS LAST, r.id`
+
+	rows, err := rm.DB.Query(context.Background(), stmt, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []*Reminder{}
+
+	for rows.Next() {
+		var reminder Reminder
+		if err := rows.Scan(reminder.scanFields()...); err != nil {
+			return nil, err
+		}
+
+		reminders = append(reminders, &reminder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+func (rm remindersModel) Insert(reminder *Reminder) error {
+	stmt := `
+WITH r AS (
+  INSERT INTO task_reminders (task_id, user_id, remind_at, offset_minutes)
+  VALUES ($1, $2, $3, $4)
+  RETURNING *
+)
+SELECT ` + reminderColumns + `
+FROM r INNER JOIN tasks t ON t.id = r.task_id`
+
+	args := []any{reminder.TaskID, reminder.UserID, reminder.RemindAt, reminder.OffsetMinutes}
+
+	return rm.DB.QueryRow(context.Background(), stmt, args...).Scan(reminder.scanFields()...)
+}
+
+func (rm remindersModel) Delete(id, taskID, userID int) error {
+	res, err := rm.DB.Exec(context.Background(), `DELETE FROM task_reminders WHERE id = $1 AND task_id = $2 AND user_id = $3`, id, taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() != 1 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ClaimDue marks up to limit due reminders as sent and returns them for
+// delivery. Rows locked by another instance are skipped, and a reminder is
+// claimed before it is sent, so a crash or restart can lose a reminder but
+// never send it twice. Reminders on finished tasks, or on tasks their user
+// can no longer read, are left alone. Reminders set for a time and those set
+// relative to the due date are looked up apart, so each can use its index.
+func (rm remindersModel) ClaimDue(limit int) ([]*DueReminder, error) {
+	stmt := `
+WITH due AS (
+  SELECT r.id
+  FROM task_reminders r
+  INNER JOIN tasks t ON t.id = r.task_id
+  WHERE r.id IN (
+      SELECT id FROM task_reminders
+      WHERE sent_at IS NULL AND remind_at <= now()
+      UNION ALL
+      SELECT o.id FROM task_reminders o
+      INNER JOIN tasks ot ON ot.id = o.task_id
+      WHERE o.sent_at IS NULL AND o.remind_at IS NULL
+        AND ot.due_at - make_interval(mins => o.offset_minutes) <= now()
+    )
+    AND r.sent_at IS NULL AND r.attempts < $2
+    AND NOT ` + taskIsDone("t") + `
+    AND ` + taskReadableBy("t", "r.user_id") + `
+  ORDER BY ` + reminderFireAt("r", "t") + `
+  LIMIT $1
+  FOR UPDATE OF r SKIP LOCKED
+)
+UPDATE task_reminders r
+SET sent_at = now(), attempts = r.attempts + 1
+FROM due, tasks t, users u
+WHERE r.id = due.id AND t.id = r.task_id AND u.id = r.user_id
+RETURNING ` + reminderColumns + `, t.title, t.due_at, u.email, u.name`
+
+	rows, err := rm.DB.Query(context.Background(), stmt, limit, MaxReminderAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []*DueReminder{}
+
+	for rows.Next() {
+		var reminder DueReminder
+		if err := rows.Scan(append(reminder.scanFields(), &reminder.TaskTitle, &reminder.DueAt, &reminder.Email, &reminder.Name)...); err != nil {
+			return nil, err
+		}
+
+		reminders = append(reminders, &reminder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// Release puts back a claimed reminder whose email could not be sent, so a
+// later poll retries it until MaxReminderAttempts is reached.
+func (rm remindersModel) Release(id int) error {
+	_, err := rm.DB.Exec(context.Background(), `UPDATE task_reminders SET sent_at = NULL WHERE id = $1`, id)
+	return err
+}
+
+// rearmReminders makes the sent offset reminders of a task fire again, for
+// when its due date moves.
+func rearmReminders(db dbtx, taskID int) error {
+	_, err := db.Exec(context.Background(), `UPDATE task_reminders SET sent_at = NULL, attempts = 0 WHERE task_id = $1 AND offset_minutes IS NOT NULL`, taskID)
+	return err
+}
+
+// copyReminders gives a task's next occurrence the same offset reminders.
+func copyReminders(db dbtx, fromID, toID int) error {
+	stmt := `
+INSERT INTO task_reminders (task_id, user_id, offset_minutes)
+SELECT $2, user_id, offset_minutes FROM task_reminders WHERE task_id = $1 AND offset_minutes IS NOT NULL`
+
+	_, err := db.Exec(context.Background(), stmt, fromID, toID)
+	return err
+}
+
+func ValidateReminder(v *validator.Validator, reminder *Reminder, task *Task) {
+	v.Check((reminder.RemindAt == nil) != (reminder.OffsetMinutes == nil), "remind_at", "exactly one of remind_at and offset_minutes must be provided")
+	if reminder.RemindAt != nil {
+		v.Check(reminder.RemindAt.After(time.Now()), "remind_at", "must be in the future")
+	}
+	if reminder.OffsetMinutes != nil {
+		v.Check(*reminder.OffsetMinutes >= 0, "offset_minutes", "must not be negative")
+		v.Check(*reminder.OffsetMinutes <= 60*24*365, "offset_minutes", "must not be more than a year")
+		v.Check(task.DueAt != nil, "offset_minutes", "the task has no due date")
+	}
+	if reminder.UserID < 1 || reminder.TaskID < 1 {
+		panic("invalid operation,reminder can't exist without a task and a user")
+	}
+}
@@ -0,0 +1,193 @@
+package data
+
+import (
+	"context"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// Kinds of search results.
+const (
+	SearchTask    = "task"
+	SearchComment = "comment"
+)
+
+// ts_headline marks matches with these control characters, which are swapped
+// for <mark> tags once the rest of the snippet has been escaped.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// SearchResult is a task or comment matching a search. Snippet is HTML with
+// the matching words wrapped in <mark>.
+type SearchResult struct {
+	Type      string    `json:"type"`
+	TaskID    int       `json:"task_id"`
+	CommentID *int      `json:"comment_id,omitempty"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SearchFilters struct {
+	Query     string
+	Type      string
+	ProjectID *int
+	Filters
+}
+
+// searchQuery turns a search box query into a to_tsquery expression. Words
+// must all match, "quoted phrases" must match in order, a trailing * matches
+// word prefixes and a leading - excludes a word or phrase. It returns an
+// empty string when the query has no word to look for.
+func searchQuery(q string) string {
+	var terms []string
+	positive := false
+
+	for len(q) > 0 {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+
+		negate := strings.HasPrefix(q, "-")
+		if negate {
+			q = q[1:]
+		}
+
+		var token string
+		var phrase bool
+
+		if rest, ok := strings.CutPrefix(q, `"`); ok {
+			token, q, _ = strings.Cut(rest, `"`)
+			phrase = true
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end < 0 {
+				end = len(q)
+			}
+			token, q = q[:end], q[end:]
+		}
+
+		prefix := !phrase && strings.HasSuffix(token, "*")
+
+		// only letters and digits reach to_tsquery, so its operators can't be
+		// injected
+		words := strings.FieldsFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		if prefix {
+			words[len(words)-1] += ":*"
+		}
+
+		term := strings.Join(words, " <-> ")
+		if len(words) > 1 {
+			term = "(" + term + ")"
+		}
+		if negate {
+			term = "!" + term
+		} else {
+			positive = true
+		}
+
+		terms = append(terms, term)
+	}
+
+	if !positive {
+		return ""
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// highlight escapes a ts_headline snippet and marks its matches.
+func highlight(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(snippet)
+}
+
+// Search ranks the tasks and the comments on tasks userID can read against
+// the query, with the same scoping as tasksModel.GetAll.
+func (t *tasksModel) Search(userID int, filters SearchFilters) ([]*SearchResult, Metadata, error) {
+	q := &queryBuilder{}
+
+	userArg := q.arg(userID)
+	query := "to_tsquery('english', " + q.arg(searchQuery(filters.Query)) + ")"
+	options := q.arg("StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=30, MinWords=10")
+	typeArg := q.arg(filters.Type)
+	projectArg := q.arg(filters.ProjectID)
+
+	// snippets are only made for the page of results
+	stmt := `
+WITH q AS (SELECT ` + query + ` AS query),
+page AS (
+  SELECT count(*) OVER() AS total, r.*
+  FROM (
+    SELECT 'task' AS type, t.id AS task_id, NULL::int AS comment_id,
+      ts_rank_cd(t.search_vector, q.query)::float8 AS rank, t.created_at
+    FROM tasks t, q
+    WHERE t.search_vector @@ q.query AND ` + taskReadableBy("t", userArg) + `
+      AND ` + typeArg + ` IN ('', 'task') AND (` + projectArg + `::int IS NULL OR t.project_id = ` + projectArg + `)
+    UNION ALL
+    SELECT 'comment', c.task_id, c.id, ts_rank_cd(c.search_vector, q.query)::float8, c.created_at
+    FROM task_comments c INNER JOIN tasks t ON t.id = c.task_id, q
+    WHERE c.search_vector @@ q.query AND ` + taskReadableBy("t", userArg) + `
+      AND ` + typeArg + ` IN ('', 'comment') AND (` + projectArg + `::int IS NULL OR t.project_id = ` + projectArg + `)
+  ) r
+  ORDER BY r.rank DESC, r.created_at DESC, r.task_id, r.comment_id NULLS FIRST
+  LIMIT ` + q.arg(filters.limit()) + ` OFFSET ` + q.arg(filters.offset()) + `
+)
+SELECT p.total, p.type, p.task_id, p.comment_id, t.title,
+  ts_headline('english', CASE WHEN p.comment_id IS NULL THEN t.title || E'\n' || t.description ELSE c.body END, q.query, ` + options + `),
+  p.rank, p.created_at
+FROM page p
+CROSS JOIN q
+INNER JOIN tasks t ON t.id = p.task_id
+LEFT JOIN task_comments c ON c.id = p.comment_id
+ORDER BY p.rank DESC, p.created_at DESC, p.task_id, p.comment_id NULLS FIRST`
+
+	rows, err := t.DB.Query(context.Background(), stmt, q.args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*SearchResult{}
+
+	for rows.Next() {
+		var result SearchResult
+		err := rows.Scan(&totalRecords, &result.Type, &result.TaskID, &result.CommentID, &result.Title, &result.Snippet, &result.Rank, &result.CreatedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		result.Snippet = highlight(result.Snippet)
+
+		results = append(results, &result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return results, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func ValidateSearchFilters(v *validator.Validator, f SearchFilters) {
+	v.Check(validator.NotEmpty(f.Query), "q", "must be provided")
+	v.Check(len(f.Query) <= 200, "q", "must not be more than 200 bytes long")
+	if validator.NotEmpty(f.Query) {
+		v.Check(searchQuery(f.Query) != "", "q", "must contain at least one word to look for")
+	}
+	if f.Type != "" {
+		v.Check(validator.PremittedValues(f.Type, []string{SearchTask, SearchComment}), "type", "must be one of `task`, `comment`")
+	}
+
+	ValidateFilters(v, f.Filters, map[string]string{})
+}
@@ -0,0 +1,198 @@
+package data
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TaskEventType is the kind of change an event records. A deleted task went
+// to the trash, a purged one is gone for good.
+type TaskEventType string
+
+const (
+	TaskCreated       TaskEventType = "created"
+	TaskUpdated       TaskEventType = "updated"
+	TaskStatusChanged TaskEventType = "status_changed"
+	TaskDeleted       TaskEventType = "deleted"
+	TaskRestored      TaskEventType = "restored"
+	TaskPurged        TaskEventType = "purged"
+	TaskArchived      TaskEventType = "archived"
+	TaskUnarchived    TaskEventType = "unarchived"
+)
+
+// FieldChange is the value of a task field before and after a change.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// TaskEvent records a change to a task. TaskTitle and ProjectID are the
+// task's at the time of the event, so events keep making sense once the task
+// is renamed, moved or deleted.
+type TaskEvent struct {
+	ID        int64                  `json:"id"`
+	TaskID    int                    `json:"task_id"`
+	TaskTitle string                 `json:"task_title"`
+	ProjectID *int                   `json:"project_id"`
+	ActorID   *int                   `json:"actor_id"`
+	ActorName *string                `json:"actor_name"`
+	Type      TaskEventType          `json:"type"`
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type taskEventsModel struct {
+	DB *pgxpool.Pool
+}
+
+const taskEventColumns = `e.id, e.task_id, e.task_title, e.project_id, e.actor_id, u.name, e.type, e.changes, e.created_at`
+
+func (event *TaskEvent) scanFields() []any {
+	return []any{&event.ID, &event.TaskID, &event.TaskTitle, &event.ProjectID, &event.ActorID, &event.ActorName, &event.Type, &event.Changes, &event.CreatedAt}
+}
+
+// recordTaskEvents records an event of actorID on each of the tasks ids, as
+// they currently are. Deletions must be recorded before the tasks are gone.
+// An actorID of 0 records a change made by a background job.
+func recordTaskEvents(db dbtx, ids []int, actorID int, eventType TaskEventType, changes map[string]FieldChange) error {
+	if changes == nil {
+		changes = map[string]FieldChange{}
+	}
+
+	stmt := `
+INSERT INTO task_events (task_id, task_title, project_id, user_id, actor_id, type, changes)
+SELECT id, title, project_id, user_id, NULLIF($2::int, 0), $3, $4 FROM tasks WHERE id = ANY($1)`
+
+	_, err := db.Exec(context.Background(), stmt, ids, actorID, eventType, changes)
+	return err
+}
+
+// recordTaskUpdate records what an update of actorID changed on a task. A
+// status change gets an event of its own, so it shows in the history even
+// when other fields changed along with it.
+func recordTaskUpdate(db dbtx, before, after *Task, actorID int) error {
+	changes := diffTask(before, after)
+
+	if status, ok := changes["status"]; ok {
+		delete(changes, "status")
+
+		err := recordTaskEvents(db, []int{after.ID}, actorID, TaskStatusChanged, map[string]FieldChange{"status": status})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return recordTaskEvents(db, []int{after.ID}, actorID, TaskUpdated, changes)
+}
+
+// diffTask returns the fields that differ between two versions of a task,
+// custom fields by "custom_fields.<key>". Labels are left out, as they belong
+// to each user rather than to the task.
+func diffTask(before, after *Task) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+
+	diff := func(field string, from, to any) {
+		if !reflect.DeepEqual(from, to) {
+			changes[field] = FieldChange{From: from, To: to}
+		}
+	}
+
+	diff("title", before.Title, after.Title)
+	diff("description", before.Description, after.Description)
+	diff("priority", before.Priority, after.Priority)
+	diff("status", before.Status, after.Status)
+	diff("project_id", before.ProjectID, after.ProjectID)
+	diff("assignee_id", before.AssigneeID, after.AssigneeID)
+	diff("start_at", utcTime(before.StartAt), utcTime(after.StartAt))
+	diff("due_at", utcTime(before.DueAt), utcTime(after.DueAt))
+	diff("recurrence", before.Recurrence, after.Recurrence)
+
+	for key, value := range before.CustomFields {
+		diff("custom_fields."+key, value, after.CustomFields[key])
+	}
+	for key, value := range after.CustomFields {
+		if _, ok := before.CustomFields[key]; !ok {
+			diff("custom_fields."+key, nil, value)
+		}
+	}
+
+	return changes
+}
+
+// utcTime makes times read from the database and from clients comparable.
+func utcTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// GetAllForTask lists a task's history, newest first. Callers must have
+// checked that the user can read the task.
+func (e taskEventsModel) GetAllForTask(taskID int, filters Filters) ([]*TaskEvent, Metadata, error) {
+	stmt := `
+SELECT count(*) OVER(), ` + taskEventColumns + `
+FROM task_events e
+LEFT JOIN users u ON u.id = e.actor_id
+WHERE e.task_id = $1
+ORDER BY e.id DESC
+LIMIT $2 OFFSET $3`
+
+	rows, err := e.DB.Query(context.Background(), stmt, taskID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return collectTaskEvents(rows, filters)
+}
+
+// GetFeed lists the events userID can see, newest first: those on their
+// tasks outside of any project, on the tasks of the projects they are a
+// member of, and their own. Events are scoped by the task's project at the
+// time, so deleted tasks keep showing up.
+func (e taskEventsModel) GetFeed(userID int, filters Filters) ([]*TaskEvent, Metadata, error) {
+	stmt := `
+SELECT count(*) OVER(), ` + taskEventColumns + `
+FROM task_events e
+LEFT JOIN users u ON u.id = e.actor_id
+WHERE e.actor_id = $1 OR ` + taskScope("e", "$1") + `
+ORDER BY e.id DESC
+LIMIT $2 OFFSET $3`
+
+	rows, err := e.DB.Query(context.Background(), stmt, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return collectTaskEvents(rows, filters)
+}
+
+func collectTaskEvents(rows pgx.Rows, filters Filters) ([]*TaskEvent, Metadata, error) {
+	defer rows.Close()
+
+	totalRecords := 0
+	events := []*TaskEvent{}
+
+	for rows.Next() {
+		var event TaskEvent
+		if err := rows.Scan(append([]any{&totalRecords}, event.scanFields()...)...); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return events, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
@@ -0,0 +1,229 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxTaskDepth is the maximum number of levels in a task hierarchy, counting
+// the root task.
+const MaxTaskDepth = 5
+
+var (
+	ErrParentNotFound = errors.New("parent task not found")
+	ErrTaskCycle      = errors.New("task can't be moved under itself or one of its subtasks")
+	ErrTaskTooDeep    = errors.New("task hierarchy is too deep")
+	ErrParentProject  = errors.New("subtasks must be in their parent's project")
+)
+
+type TaskProgress struct {
+	Done    int `json:"done"`
+	Total   int `json:"total"`
+	Percent int `json:"percent"`
+}
+
+// TaskNode is a task together with its nested subtasks.
+type TaskNode struct {
+	*Task
+	Children []*TaskNode `json:"children"`
+}
+
+// checkParent makes sure parentID can become the parent of taskID, which is 0
+// for a task that doesn't exist yet: the user must be able to see the parent,
+// it must be in the same project as the task, it must not be the task or one
+// of its descendants, and the moved subtree must still fit within
+// MaxTaskDepth.
+func checkParent(db dbtx, parentID, taskID, userID int, projectID *int) error {
+	// serialize hierarchy changes so concurrent moves can't build a cycle
+	_, err := db.Exec(context.Background(), `SELECT pg_advisory_xact_lock(hashtext('tasks_hierarchy'))`)
+	if err != nil {
+		return err
+	}
+
+	var parentProjectID *int
+	err = db.QueryRow(context.Background(), `SELECT t.project_id FROM tasks t WHERE t.id = $1 AND `+taskReadableBy("t", "$2"), parentID, userID).Scan(&parentProjectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrParentNotFound
+		}
+		return err
+	}
+
+	if !equalIntPtr(parentProjectID, projectID) {
+		return ErrParentProject
+	}
+
+	stmt := `
+WITH RECURSIVE ancestors AS (
+  SELECT id, parent_id, 1 AS depth FROM tasks WHERE id = $1
+  UNION ALL
+  SELECT t.id, t.parent_id, a.depth + 1 FROM tasks t INNER JOIN ancestors a ON t.id = a.parent_id
+)
+SELECT max(depth), bool_or(id = $2) FROM ancestors`
+
+	var parentDepth int
+	var cycle bool
+
+	err = db.QueryRow(context.Background(), stmt, parentID, taskID).Scan(&parentDepth, &cycle)
+	if err != nil {
+		return err
+	}
+
+	if cycle {
+		return ErrTaskCycle
+	}
+
+	height := 1
+	if taskID != 0 {
+		stmt = `
+WITH RECURSIVE subtree AS (
+  SELECT id, 1 AS depth FROM tasks WHERE id = $1
+  UNION ALL
+  SELECT t.id, s.depth + 1 FROM tasks t INNER JOIN subtree s ON t.parent_id = s.id
+)
+SELECT max(depth) FROM subtree`
+
+		err = db.QueryRow(context.Background(), stmt, taskID).Scan(&height)
+		if err != nil {
+			return err
+		}
+	}
+
+	if parentDepth+height > MaxTaskDepth {
+		return ErrTaskTooDeep
+	}
+
+	return nil
+}
+
+// taskSubtree returns the ids of all the descendants of the tasks ids.
+func taskSubtree(db dbtx, ids ...int) ([]int, error) {
+	stmt := `
+WITH RECURSIVE subtree AS (
+  SELECT id FROM tasks WHERE parent_id = ANY($1)
+  UNION ALL
+  SELECT c.id FROM tasks c INNER JOIN subtree s ON c.parent_id = s.id
+)
+SELECT id FROM subtree`
+
+	rows, err := db.Query(context.Background(), stmt, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+func (t *tasksModel) GetChildren(id, userID int) ([]*Task, error) {
+	stmt := `SELECT ` + taskColumns("$2") + ` FROM tasks t WHERE t.parent_id = $1 AND ` + taskReadableBy("t", "$2") + ` ORDER BY t.id`
+
+	rows, err := t.DB.Query(context.Background(), stmt, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []*Task{}
+
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(task.scanFields()...); err != nil {
+			return nil, err
+		}
+		task.afterScan()
+
+		tasks = append(tasks, &task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// GetTree returns the task with the given id and all of its descendants.
+func (t *tasksModel) GetTree(id, userID int) (*TaskNode, error) {
+	stmt := fmt.Sprintf(`
+WITH RECURSIVE subtree AS (
+  SELECT t.id FROM tasks t WHERE t.id = $1 AND %s
+  UNION ALL
+  SELECT c.id FROM tasks c INNER JOIN subtree s ON c.parent_id = s.id WHERE c.deleted_at IS NULL
+)
+SELECT %s FROM tasks t INNER JOIN subtree s ON t.id = s.id
+ORDER BY t.id`, taskReadableBy("t", "$2"), taskColumns("$2"))
+
+	rows, err := t.DB.Query(context.Background(), stmt, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := map[int]*TaskNode{}
+	var order []*TaskNode
+
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(task.scanFields()...); err != nil {
+			return nil, err
+		}
+		task.afterScan()
+
+		node := &TaskNode{Task: &task, Children: []*TaskNode{}}
+		nodes[task.ID] = node
+		order = append(order, node)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	root, ok := nodes[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	for _, node := range order {
+		if node.ID == id || node.ParentID == nil {
+			continue
+		}
+		if parent, ok := nodes[*node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return root, nil
+}
+
+// Move re-parents a task and its whole subtree on behalf of userID. A nil
+// parentID turns the task into a root task.
+func (t *tasksModel) Move(id, userID int, parentID *int) error {
+	return pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		var projectID, previous *int
+
+		stmt := `SELECT t.project_id, t.parent_id FROM tasks t WHERE t.id = $1 AND ` + taskWritableBy("t", "$2") + ` FOR UPDATE`
+		err := tx.QueryRow(context.Background(), stmt, id, userID).Scan(&projectID, &previous)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		if parentID != nil {
+			if err := checkParent(tx, *parentID, id, userID, projectID); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(context.Background(), `UPDATE tasks SET parent_id = $1, version = version + 1 WHERE id = $2`, parentID, id)
+		if err != nil {
+			return err
+		}
+
+		if equalIntPtr(previous, parentID) {
+			return nil
+		}
+
+		return recordTaskEvents(tx, []int{id}, userID, TaskUpdated, map[string]FieldChange{"parent_id": {From: previous, To: parentID}})
+	})
+}
@@ -3,16 +3,25 @@ package data
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/rrule"
 	"github.com/moutafatin/go-tasks-management-api/internal/validator"
 )
 
-var ErrRecordNotFound = errors.New("record not found")
+var (
+	ErrRecordNotFound = errors.New("record not found")
+	ErrEditConflict   = errors.New("edit conflict")
+)
 
+// TaskStatus is the name of a state of the task's workflow. The constants
+// are the states of the default workflow.
 type TaskStatus string
 
 const (
@@ -29,22 +38,13 @@ const (
 	TaskPriorityHigh   TaskPriority = "HIGH"
 )
 
+// GetTaskStatus reads the status given by a client, a workflow state name in
+// any case. A missing status is empty, for the workflow's initial one.
 func GetTaskStatus(status *string) TaskStatus {
 	if status == nil {
-		return taskStatusTodo
-	}
-	switch strings.ToLower(*status) {
-	case "todo":
-		return taskStatusTodo
-	case "in_progress":
-		return taskStatusInProgress
-	case "done":
-		return taskStatusDone
-	case "":
 		return ""
-	default:
-		return TaskStatus("invalid")
 	}
+	return TaskStatus(strings.ToUpper(strings.TrimSpace(*status)))
 }
 
 func GetTaskPriority(priority *string) TaskPriority {
@@ -71,80 +71,780 @@ type Task struct {
 	Description string
 	Priority    TaskPriority
 	Status      TaskStatus
-	UserID      int       `db:"user_id"`
-	CreatedAt   time.Time `db:"created_at"`
+	ProjectID   *int       `json:"project_id"`
+	ParentID    *int       `json:"parent_id"`
+	AssigneeID  *int       `json:"assignee_id"`
+	Labels      []string   `json:"labels"`
+	StartAt     *time.Time `json:"start_at"`
+	DueAt       *time.Time `json:"due_at"`
+	IsOverdue   bool       `json:"is_overdue"`
+	Position    string     `json:"position"`
+	// CustomFields holds the values of the project's custom fields by key.
+	CustomFields map[string]any `json:"custom_fields"`
+	// Recurrence is an RFC 5545 RRULE. RecurrenceStart anchors the series,
+	// so COUNT keeps counting from the first occurrence.
+	Recurrence       *string       `json:"recurrence"`
+	RecurrenceStart  *time.Time    `json:"-"`
+	NextOccurrenceID *int          `json:"next_occurrence_id"`
+	Progress         *TaskProgress `json:"progress,omitempty"`
+	// ChecklistProgress is nil when the task has no checklist.
+	ChecklistProgress *ChecklistProgress `json:"checklist_progress"`
+	UserID            int                `db:"user_id"`
+	ArchivedAt        *time.Time         `json:"archived_at"`
+	CreatedAt         time.Time          `db:"created_at"`
+	// DeletedAt is set on tasks in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version goes up with every change to the task.
+	Version int `json:"version"`
 }
 
 type tasksModel struct {
 	DB *pgxpool.Pool
 }
 
-func (t *tasksModel) GetAll(userID int) ([]*Task, error) {
-	stmt := `SELECT id, title, description, priority, status,user_id, created_at FROM tasks WHERE user_id = $1`
+type TaskFilters struct {
+	Statuses   []TaskStatus
+	Priorities []TaskPriority
+	Search     string
+	DueBefore  *time.Time
+	DueAfter   *time.Time
+	Overdue    *bool
+	Due        string
+	Labels     []string
+	LabelMatch string
+	ProjectID  *int
+	Inbox      bool
+	AssigneeID *int
+	// Archived picks archived or active tasks, nil lists both.
+	Archived *bool
+	// Fields filter on custom fields, which are resolved against
+	// CustomFields, the fields of the project the tasks are listed from.
+	Fields       []FieldFilter
+	CustomFields []*CustomField
+	Filters
+
+	// UseCursor switches the listing to keyset pagination over
+	// (created_at, id). Cursor is nil for the first page.
+	UseCursor bool
+	Cursor    *Cursor
+}
+
+var taskSortColumns = map[string]string{
+	"id":         "t.id",
+	"title":      "t.title",
+	"priority":   "t.priority",
+	"status":     taskStatusOrder("t"),
+	"created_at": "t.created_at",
+	"start_at":   "t.start_at",
+	"due_at":     "t.due_at",
+}
+
+// Values accepted by the ?due= shortcut filter.
+const (
+	DueToday    = "today"
+	DueTomorrow = "tomorrow"
+	DueThisWeek = "this_week"
+	DueNone     = "none"
+)
+
+// UsesCustomFields reports whether the filters or the sort refer to custom
+// fields, which the caller must then load into CustomFields.
+func (f TaskFilters) UsesCustomFields() bool {
+	if len(f.Fields) > 0 {
+		return true
+	}
+	for _, key := range f.Sort {
+		if strings.HasPrefix(strings.TrimPrefix(key, "-"), customFieldKeyPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortColumns returns taskSortColumns extended with the project's custom
+// fields, sorted on as field.<key>.
+func (f TaskFilters) sortColumns() map[string]string {
+	if len(f.CustomFields) == 0 {
+		return taskSortColumns
+	}
+
+	columns := maps.Clone(taskSortColumns)
+	for _, field := range f.CustomFields {
+		columns[customFieldKeyPrefix+field.Key] = field.value("t")
+	}
+
+	return columns
+}
+
+// taskIsDone returns the SQL condition for a finished task, one in a terminal
+// state of its workflow.
+func taskIsDone(alias string) string {
+	return "COALESCE(" + workflowState(alias, "is_terminal") + ", " + alias + ".status = '" + string(taskStatusDone) + "')"
+}
+
+// taskStatusOrder returns the SQL expression sorting tasks by the position
+// of their status in the workflow.
+func taskStatusOrder(alias string) string {
+	return "COALESCE(" + workflowState(alias, "position") + ", array_position(ARRAY['" + string(taskStatusTodo) + "', '" + string(taskStatusInProgress) + "', '" + string(taskStatusDone) + "'], " + alias + ".status))"
+}
+
+// filterQuery builds the conditions shared by the offset and keyset listings.
+// The user id is always the first argument, $1.
+func (f TaskFilters) filterQuery(userID int) *queryBuilder {
+	q := &queryBuilder{}
+
+	q.where(taskReadableBy("t", q.arg(userID)))
+
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, status := range f.Statuses {
+			statuses[i] = string(status)
+		}
+		q.where("t.status::text = ANY(" + q.arg(statuses) + "::text[])")
+	}
+
+	if len(f.Priorities) > 0 {
+		priorities := make([]string, len(f.Priorities))
+		for i, priority := range f.Priorities {
+			priorities[i] = string(priority)
+		}
+		q.where("t.priority::text = ANY(" + q.arg(priorities) + "::text[])")
+	}
+
+	if f.Search != "" {
+		pattern := q.arg("%" + escapeLike(f.Search) + "%")
+		q.where("(t.title ILIKE " + pattern + " OR t.description ILIKE " + pattern + ")")
+	}
+
+	if f.DueBefore != nil {
+		q.where("t.due_at < " + q.arg(*f.DueBefore))
+	}
+
+	if f.DueAfter != nil {
+		q.where("t.due_at >= " + q.arg(*f.DueAfter))
+	}
+
+	if f.Overdue != nil {
+		q.where(taskIsOverdue("t") + " = " + q.arg(*f.Overdue))
+	}
+
+	switch {
+	case f.ProjectID != nil:
+		q.where("t.project_id = " + q.arg(*f.ProjectID))
+	case f.Inbox:
+		q.where("t.project_id IS NULL")
+	}
+
+	if f.AssigneeID != nil {
+		q.where("t.assignee_id = " + q.arg(*f.AssigneeID))
+	}
+
+	if f.Archived != nil {
+		q.where("(t.archived_at IS NOT NULL) = " + q.arg(*f.Archived))
+	}
+
+	for _, filter := range f.Fields {
+		filter.where(q, fieldByKey(f.CustomFields, filter.Key))
+	}
+
+	// repeated names would never all match, as labels are counted once
+	if labels := NormalizeLabelNames(f.Labels); len(labels) > 0 {
+		lowered := make([]string, len(labels))
+		for i, name := range labels {
+			lowered[i] = strings.ToLower(name)
+		}
+
+		matching := fmt.Sprintf(`
+SELECT count(DISTINCT l.id) FROM task_labels tl INNER JOIN labels l ON l.id = tl.label_id
+WHERE tl.task_id = t.id AND l.user_id = $1 AND lower(l.name::text) = ANY(%s::text[])`, q.arg(lowered))
+
+		if f.LabelMatch == LabelMatchAll {
+			q.where(fmt.Sprintf("(%s) = %s", matching, q.arg(len(lowered))))
+		} else {
+			q.where(fmt.Sprintf("(%s) > 0", matching))
+		}
+	}
+
+	switch f.Due {
+	case DueNone:
+		q.where("t.due_at IS NULL")
+	case DueToday, DueTomorrow, DueThisWeek:
+		from, to := dueRange(f.Due, time.Now().UTC())
+		q.where(fmt.Sprintf("t.due_at >= %s AND t.due_at < %s", q.arg(from), q.arg(to)))
+	}
+
+	return q
+}
+
+// dueRange returns the [from, to) interval covered by a ?due= shortcut. Weeks
+// start on Monday.
+func dueRange(due string, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch due {
+	case DueTomorrow:
+		return today.AddDate(0, 0, 1), today.AddDate(0, 0, 2)
+	case DueThisWeek:
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return monday, monday.AddDate(0, 0, 7)
+	default:
+		return today, today.AddDate(0, 0, 1)
+	}
+}
+
+// taskIsOverdue returns the SQL expression behind Task.IsOverdue.
+func taskIsOverdue(alias string) string {
+	return fmt.Sprintf("(COALESCE(%[1]s.due_at < now(), false) AND NOT %[2]s)", alias, taskIsDone(alias))
+}
+
+// taskColumns returns the select list for a task as seen by the user behind
+// userArg. Labels are personal, so only that user's labels are included.
+func taskColumns(userArg string) string {
+	return `t.id, t.title, t.description, t.priority, t.status, t.project_id, t.parent_id, t.assignee_id,
+ARRAY(SELECT l.name::text FROM task_labels tl INNER JOIN labels l ON l.id = tl.label_id WHERE tl.task_id = t.id AND l.user_id = ` + userArg + ` ORDER BY l.name),
+t.start_at, t.due_at, ` + taskIsOverdue("t") + `, t.position, t.custom_fields,
+t.recurrence, t.recurrence_start, (SELECT n.id FROM tasks n WHERE n.recurrence_prev_id = t.id AND n.deleted_at IS NULL),
+(SELECT count(*) FILTER (WHERE ` + taskIsDone("c") + `) FROM tasks c WHERE c.parent_id = t.id AND c.deleted_at IS NULL),
+(SELECT count(*) FROM tasks c WHERE c.parent_id = t.id AND c.deleted_at IS NULL),
+(SELECT count(*) FILTER (WHERE ci.checked) FROM task_checklist_items ci WHERE ci.task_id = t.id),
+(SELECT count(*) FROM task_checklist_items ci WHERE ci.task_id = t.id),
+t.user_id, t.archived_at, t.created_at, t.deleted_at, t.version`
+}
+
+// scanFields returns the scan destinations matching taskColumns. Call
+// afterScan once the row has been scanned.
+func (task *Task) scanFields() []any {
+	task.Progress = &TaskProgress{}
+	task.ChecklistProgress = &ChecklistProgress{}
+
+	return []any{
+		&task.ID, &task.Title, &task.Description, &task.Priority, &task.Status, &task.ProjectID, &task.ParentID, &task.AssigneeID, &task.Labels, &task.StartAt, &task.DueAt, &task.IsOverdue, &task.Position, &task.CustomFields,
+		&task.Recurrence, &task.RecurrenceStart, &task.NextOccurrenceID,
+		&task.Progress.Done, &task.Progress.Total,
+		&task.ChecklistProgress.Checked, &task.ChecklistProgress.Total,
+		&task.UserID, &task.ArchivedAt, &task.CreatedAt, &task.DeletedAt, &task.Version,
+	}
+}
+
+func (task *Task) afterScan() {
+	if task.ChecklistProgress.Total == 0 {
+		task.ChecklistProgress = nil
+	}
+
+	if task.Progress.Total == 0 {
+		task.Progress = nil
+		return
+	}
+
+	task.Progress.Percent = task.Progress.Done * 100 / task.Progress.Total
+}
+
+func (t *tasksModel) GetAll(userID int, filters TaskFilters) ([]*Task, Metadata, error) {
+	q := filters.filterQuery(userID)
+
+	stmt := fmt.Sprintf(`
+SELECT count(*) OVER(), %s
+FROM tasks t
+%s
+ORDER BY %s
+LIMIT %s OFFSET %s`, taskColumns("$1"), q.whereClause(), filters.orderBy(filters.sortColumns(), "t.id"), q.arg(filters.limit()), q.arg(filters.offset()))
 
-	rows, err := t.DB.Query(context.Background(), stmt, userID)
+	rows, err := t.DB.Query(context.Background(), stmt, q.args...)
 	if err != nil {
-		return nil, err
+		return nil, Metadata{}, err
 	}
+	defer rows.Close()
+
+	totalRecords := 0
+	tasks := []*Task{}
+
+	for rows.Next() {
+		var task Task
+		err := rows.Scan(append([]any{&totalRecords}, task.scanFields()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		task.afterScan()
 
-	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[Task])
+		tasks = append(tasks, &task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return tasks, metadata, nil
+}
+
+// GetAllByCursor lists tasks in (created_at, id) order, starting after
+// filters.Cursor. Unlike GetAll it doesn't count the matching rows and stays
+// stable when tasks are inserted between pages.
+func (t *tasksModel) GetAllByCursor(userID int, filters TaskFilters) ([]*Task, CursorMetadata, error) {
+	q := filters.filterQuery(userID)
+
+	if filters.Cursor != nil {
+		q.where(fmt.Sprintf("(t.created_at, t.id) > (%s, %s)", q.arg(filters.Cursor.CreatedAt), q.arg(filters.Cursor.ID)))
+	}
+
+	// fetch one extra row to find out whether there is a next page
+	stmt := fmt.Sprintf(`
+SELECT %s
+FROM tasks t
+%s
+ORDER BY t.created_at ASC, t.id ASC
+LIMIT %s`, taskColumns("$1"), q.whereClause(), q.arg(filters.limit()+1))
+
+	rows, err := t.DB.Query(context.Background(), stmt, q.args...)
+	if err != nil {
+		return nil, CursorMetadata{}, err
+	}
+	defer rows.Close()
+
+	tasks := []*Task{}
+
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(task.scanFields()...); err != nil {
+			return nil, CursorMetadata{}, err
+		}
+		task.afterScan()
+
+		tasks = append(tasks, &task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, CursorMetadata{}, err
+	}
+
+	metadata := CursorMetadata{PageSize: filters.PageSize}
+
+	if len(tasks) > filters.limit() {
+		tasks = tasks[:filters.limit()]
+		last := tasks[len(tasks)-1]
+		next := Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+		metadata.NextCursor = &next
+	}
+
+	return tasks, metadata, nil
 }
 
 func (t *tasksModel) GetByID(id, userID int) (*Task, error) {
-	stmt := `SELECT id, title, description, priority, status, user_id, created_at FROM tasks WHERE id = $1 AND user_id = $2`
+	return getTask(t.DB, id, userID)
+}
+
+func getTask(db dbtx, id, userID int) (*Task, error) {
+	stmt := `SELECT ` + taskColumns("$2") + ` FROM tasks t WHERE t.id = $1 AND ` + taskReadableBy("t", "$2")
 
-	row := t.DB.QueryRow(context.Background(), stmt, id, userID)
+	row := db.QueryRow(context.Background(), stmt, id, userID)
 
 	var task Task
-	err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Priority, &task.Status, &task.UserID, &task.CreatedAt)
+	err := row.Scan(task.scanFields()...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrRecordNotFound
 		}
 		return nil, err
 	}
+	task.afterScan()
 
 	return &task, nil
 }
 
+// Insert creates the task on behalf of task.UserID, who must be able to edit
+// the task's project.
 func (t *tasksModel) Insert(task *Task) error {
-	stmt := `INSERT INTO tasks (title, description, priority, status,user_id) VALUES ($1, $2, $3, $4,$5) RETURNING id, created_at`
+	stmt := `
+INSERT INTO tasks AS t (title, description, priority, status, project_id, parent_id, start_at, due_at, recurrence, recurrence_start, position, custom_fields, user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+RETURNING t.id, t.created_at, t.version, ` + taskIsOverdue("t")
+
+	task.StartAt, task.DueAt = truncateSecond(task.StartAt), truncateSecond(task.DueAt)
+
+	task.RecurrenceStart = nil
+	if task.Recurrence != nil {
+		task.RecurrenceStart = task.DueAt
+	}
+	if task.CustomFields == nil {
+		task.CustomFields = map[string]any{}
+	}
 
-	args := []any{task.Title, task.Description, task.Priority, task.Status, task.UserID}
+	return pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		if task.ProjectID != nil {
+			if err := checkProject(tx, *task.ProjectID, task.UserID); err != nil {
+				return err
+			}
+		}
+		if task.ParentID != nil {
+			if err := checkParent(tx, *task.ParentID, 0, task.UserID, task.ProjectID); err != nil {
+				return err
+			}
+		}
 
-	return t.DB.QueryRow(context.Background(), stmt, args...).Scan(&task.ID, &task.CreatedAt)
+		err := lockBoard(tx, task.ProjectID, task.UserID)
+		if err != nil {
+			return err
+		}
+
+		workflow, err := workflowFor(tx, task.ProjectID, task.UserID)
+		if err != nil {
+			return err
+		}
+		if err := checkWorkflowStatus(workflow, "", task.Status); err != nil {
+			return err
+		}
+
+		task.Position, err = nextPosition(tx, task.ProjectID, task.UserID, task.Status)
+		if err != nil {
+			return err
+		}
+
+		args := []any{task.Title, task.Description, task.Priority, task.Status, task.ProjectID, task.ParentID, task.StartAt, task.DueAt, task.Recurrence, task.RecurrenceStart, task.Position, task.CustomFields, task.UserID}
+
+		err = tx.QueryRow(context.Background(), stmt, args...).Scan(&task.ID, &task.CreatedAt, &task.Version, &task.IsOverdue)
+		if err != nil {
+			return err
+		}
+
+		err = t.syncLabels(tx, task, task.UserID)
+		if err != nil {
+			return err
+		}
+
+		return recordTaskEvents(tx, []int{task.ID}, task.UserID, TaskCreated, nil)
+	})
+}
+
+// Delete moves the task and its subtasks to the trash on behalf of userID,
+// provided the task is still at version. Subtasks already in the trash keep
+// the time they were trashed at.
+func (t *tasksModel) Delete(id, version, userID int) error {
+	return deleteTask(t.DB, id, version, userID)
 }
 
-func (t *tasksModel) Delete(id, userID int) error {
-	stmt := `DELETE FROM tasks WHERE id = $1 AND user_id = $2`
-	res, err := t.DB.Exec(context.Background(), stmt, id, userID)
+// deleteTask runs Delete on db, in a savepoint when db is a transaction. A
+// version of 0 deletes the task whatever its version.
+func deleteTask(db dbtx, id, version, userID int) error {
+	return pgx.BeginFunc(context.Background(), db, func(tx pgx.Tx) error {
+		var current int
+
+		stmt := `SELECT t.version FROM tasks t WHERE t.id = $1 AND ` + taskWritableBy("t", "$2") + ` FOR UPDATE`
+		err := tx.QueryRow(context.Background(), stmt, id, userID).Scan(&current)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		if version != 0 && current != version {
+			return ErrEditConflict
+		}
+
+		subtree, err := taskSubtree(tx, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(context.Background(), `UPDATE tasks SET deleted_at = now(), version = version + 1 WHERE id = ANY($1) AND deleted_at IS NULL RETURNING id`, append(subtree, id))
+		if err != nil {
+			return err
+		}
+
+		trashed, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		return recordTaskEvents(tx, trashed, userID, TaskDeleted, nil)
+	})
+}
+
+// Update saves the task on behalf of userID, who needs write access to it and
+// to the project it is moved to. Subtasks follow their parent into the new
+// project. The status must be a state of the workflow of the task's board,
+// reachable from the current one. Completing a recurring task creates its
+// next occurrence, and a task can't leave its initial state while it is
+// blocked. The task must still be at task.Version, which is set to the new
+// version.
+func (t *tasksModel) Update(task *Task, userID int) error {
+	return t.update(t.DB, task, userID)
+}
+
+// update runs Update on db, in a savepoint when db is a transaction.
+func (t *tasksModel) update(db dbtx, task *Task, userID int) error {
+	stmt := `
+UPDATE tasks AS t
+SET title = $1, description = $2, priority = $3, status = $4, project_id = $5, start_at = $6, due_at = $7, recurrence = $8, recurrence_start = $9, position = $10, custom_fields = $11, version = t.version + 1
+WHERE t.id = $12 AND ` + taskWritableBy("t", "$13") + `
+RETURNING ` + taskIsOverdue("t")
+
+	task.StartAt, task.DueAt = truncateSecond(task.StartAt), truncateSecond(task.DueAt)
+
+	return pgx.BeginFunc(context.Background(), db, func(tx pgx.Tx) error {
+		// before holds the fields the history records changes of
+		var before Task
+		var done bool
+
+		err := tx.QueryRow(context.Background(), `
+SELECT t.title, t.description, t.priority, t.status, t.project_id, t.parent_id, t.assignee_id, t.start_at, t.due_at, t.recurrence, t.custom_fields, t.version, `+taskIsDone("t")+`
+FROM tasks t WHERE t.id = $1 FOR UPDATE`, task.ID).Scan(&before.Title, &before.Description, &before.Priority, &before.Status, &before.ProjectID, &before.ParentID, &before.AssigneeID, &before.StartAt, &before.DueAt, &before.Recurrence, &before.CustomFields, &before.Version, &done)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+		if before.Version != task.Version {
+			return ErrEditConflict
+		}
+
+		// a new rule starts a new series at the current due date
+		switch {
+		case task.Recurrence == nil:
+			task.RecurrenceStart = nil
+		case before.Recurrence == nil || *before.Recurrence != *task.Recurrence:
+			task.RecurrenceStart = task.DueAt
+		}
+
+		projectChanged := !equalIntPtr(before.ProjectID, task.ProjectID)
+
+		if projectChanged {
+			if before.ParentID != nil {
+				return ErrParentProject
+			}
+			if task.ProjectID != nil {
+				if err := checkProject(tx, *task.ProjectID, userID); err != nil {
+					return err
+				}
+			}
+		}
+
+		// the workflow only changes under the board lock, which a task
+		// changing column takes anyway
+		var workflow *Workflow
+
+		if projectChanged || task.Status != before.Status {
+			if err := lockBoard(tx, task.ProjectID, task.UserID); err != nil {
+				return err
+			}
+
+			workflow, err = workflowFor(tx, task.ProjectID, task.UserID)
+			if err != nil {
+				return err
+			}
+
+			// the transition rules don't apply across workflows
+			from := before.Status
+			if projectChanged {
+				from = ""
+			}
+			if err := checkWorkflowStatus(workflow, from, task.Status); err != nil {
+				return err
+			}
+
+			if task.Status != before.Status && workflow.needsUnblocked(task.Status) {
+				if err := checkUnblocked(tx, task.ID); err != nil {
+					return err
+				}
+			}
+
+			// a task changing column goes to the end of its new one
+			task.Position, err = nextPosition(tx, task.ProjectID, task.UserID, task.Status)
+			if err != nil {
+				return err
+			}
+		}
+
+		args := []any{task.Title, task.Description, task.Priority, task.Status, task.ProjectID, task.StartAt, task.DueAt, task.Recurrence, task.RecurrenceStart, task.Position, task.CustomFields, task.ID, userID}
+
+		err = tx.QueryRow(context.Background(), stmt, args...).Scan(&task.IsOverdue)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		if projectChanged {
+			subtree, err := taskSubtree(tx, task.ID)
+			if err != nil {
+				return err
+			}
+
+			// subtasks keep whether they are done in the new workflow
+			if err := fitStatuses(tx, workflow, subtree); err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(context.Background(), `UPDATE tasks SET project_id = $2, version = version + 1 WHERE id = ANY($1)`, subtree, task.ProjectID); err != nil {
+				return err
+			}
+
+			if len(subtree) > 0 {
+				err = recordTaskEvents(tx, subtree, userID, TaskUpdated, map[string]FieldChange{"project_id": {From: before.ProjectID, To: task.ProjectID}})
+				if err != nil {
+					return err
+				}
+			}
+
+			// custom fields are per project, only the values the new one
+			// has a field for are kept
+			if err := fitCustomFields(tx, append(subtree, task.ID)); err != nil {
+				return err
+			}
+			err = tx.QueryRow(context.Background(), `SELECT custom_fields FROM tasks WHERE id = $1`, task.ID).Scan(&task.CustomFields)
+			if err != nil {
+				return err
+			}
+
+			// assignees who can't see the tasks in their new scope lose them
+			stmt := `
+WITH RECURSIVE subtree AS (
+  SELECT id FROM tasks WHERE id = $1
+  UNION ALL
+  SELECT c.id FROM tasks c INNER JOIN subtree s ON c.parent_id = s.id
+)
+UPDATE tasks t SET assignee_id = NULL, version = t.version + 1
+WHERE t.id IN (SELECT id FROM subtree) AND t.assignee_id IS NOT NULL AND NOT ` + taskReadableBy("t", "t.assignee_id") + `
+RETURNING t.id`
+
+			rows, err := tx.Query(context.Background(), stmt, task.ID)
+			if err != nil {
+				return err
+			}
+
+			unassigned, err := pgx.CollectRows(rows, pgx.RowTo[int])
+			if err != nil {
+				return err
+			}
+
+			if slices.Contains(unassigned, task.ID) {
+				task.AssigneeID = nil
+			}
+		}
+
+		err = t.syncLabels(tx, task, userID)
+		if err != nil {
+			return err
+		}
+
+		dueChanged := (before.DueAt == nil) != (task.DueAt == nil) || (task.DueAt != nil && !task.DueAt.Equal(*before.DueAt))
+		if dueChanged {
+			if err := rearmReminders(tx, task.ID); err != nil {
+				return err
+			}
+		}
+
+		err = recordTaskUpdate(tx, &before, task, userID)
+		if err != nil {
+			return err
+		}
+
+		if task.Recurrence != nil && !done && workflow != nil && workflow.IsTerminal(task.Status) {
+			if err := t.insertNextOccurrence(tx, task, userID); err != nil {
+				return err
+			}
+		}
+
+		// moving the subtree changes the task again
+		return tx.QueryRow(context.Background(), `SELECT version FROM tasks WHERE id = $1`, task.ID).Scan(&task.Version)
+	})
+}
+
+// syncLabels makes task.Labels the user's labels on the task, creating the
+// ones the user doesn't have yet, and reloads them so the task carries the
+// stored spelling. Labels other users put on a shared task are left alone.
+func (t *tasksModel) syncLabels(db dbtx, task *Task, userID int) error {
+	err := setTaskLabels(db, task.ID, userID, task.Labels)
 	if err != nil {
 		return err
 	}
 
-	if res.RowsAffected() != 1 {
-		return ErrRecordNotFound
-	}
-	return nil
-}
+	stmt := `SELECT ARRAY(SELECT l.name::text FROM task_labels tl INNER JOIN labels l ON l.id = tl.label_id WHERE tl.task_id = $1 AND l.user_id = $2 ORDER BY l.name)`
 
-func (t *tasksModel) Update(task *Task) error {
-	stmt := `UPDATE tasks SET title = $1, description = $2, priority = $3, status = $4 WHERE id = $5 and user_id = $6`
-	args := []any{task.Title, task.Description, task.Priority, task.Status, task.ID, task.UserID}
+	return db.QueryRow(context.Background(), stmt, task.ID, userID).Scan(&task.Labels)
+}
 
-	_, err := t.DB.Exec(context.Background(), stmt, args...)
+// truncateSecond drops the fraction of a second of t, which the task date
+// columns don't keep. Dates are compared and stored that way, so the
+// database never rounds two valid dates onto each other.
+func truncateSecond(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	truncated := t.Truncate(time.Second)
+	return &truncated
+}
 
-	return err
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
-func ValidateTask(v *validator.Validator, task *Task) {
+// ValidateTask checks the task against the workflow of the board it is on.
+func ValidateTask(v *validator.Validator, task *Task, workflow *Workflow) {
 	v.Check(validator.NotEmpty(task.Title), "title", "title is required")
 	v.Check(validator.NotEmpty(task.Description), "description", "description is required")
 	v.Check(validator.NotEmpty(string(task.Priority)), "priority", "priority must not be empty if set")
 	v.Check(validator.PremittedValues(task.Priority, []TaskPriority{TaskPriorityLow, TaskPriorityMedium, TaskPriorityHigh}), "priority", "Invalid priority value, must be one of `low`, `medium`, `high")
-	v.Check(validator.NotEmpty(string(task.Status)), "status", "status must not be empty if set")
-	v.Check(validator.PremittedValues(task.Status, []TaskStatus{taskStatusTodo, taskStatusInProgress, taskStatusDone}), "status", "Invalid status value, must be one of `todo`, `in_progress`, `done`")
+	ValidateTaskStatus(v, task.Status)
+	if v.Valid() {
+		v.Check(workflow.HasStatus(task.Status), "status", "is not a state of the task's workflow")
+	}
+	for _, name := range task.Labels {
+		ValidateLabelName(v, "labels", name)
+	}
+	if task.StartAt != nil && task.DueAt != nil {
+		v.Check(truncateSecond(task.StartAt).Before(*truncateSecond(task.DueAt)), "start_at", "start_at must be before due_at")
+	}
+	if task.Recurrence != nil {
+		if _, err := rrule.Parse(*task.Recurrence); err != nil {
+			v.AddError("recurrence", err.Error())
+		}
+		v.Check(task.DueAt != nil, "due_at", "due_at is required for a recurring task")
+	}
 	if task.UserID < 1 {
 		panic("invalid operation,task can't exist without a user")
 	}
 }
+
+func ValidateTaskFilters(v *validator.Validator, f TaskFilters) {
+	for _, status := range f.Statuses {
+		ValidateTaskStatus(v, status)
+	}
+	if f.UsesCustomFields() {
+		v.Check(f.ProjectID != nil, "project", "must be set to filter or sort on custom fields")
+	}
+	for _, filter := range f.Fields {
+		ValidateFieldFilter(v, filter, f.CustomFields)
+	}
+	for _, priority := range f.Priorities {
+		v.Check(validator.PremittedValues(priority, []TaskPriority{TaskPriorityLow, TaskPriorityMedium, TaskPriorityHigh}), "priority", "Invalid priority value, must be one of `low`, `medium`, `high")
+	}
+	v.Check(len(f.Search) <= 200, "q", "must not be more than 200 bytes long")
+	if f.LabelMatch != "" {
+		v.Check(validator.PremittedValues(f.LabelMatch, []string{LabelMatchAny, LabelMatchAll}), "label_match", "must be one of `any`, `all`")
+	}
+	if f.Due != "" {
+		v.Check(validator.PremittedValues(f.Due, []string{DueToday, DueTomorrow, DueThisWeek, DueNone}), "due", "must be one of `today`, `tomorrow`, `this_week`, `none`")
+	}
+	if f.DueBefore != nil && f.DueAfter != nil {
+		v.Check(f.DueAfter.Before(*f.DueBefore), "due_after", "must be before due_before")
+	}
+
+	if f.UseCursor {
+		v.Check(f.Page == 1, "page", "cannot be combined with cursor")
+		v.Check(len(f.Sort) == 0, "sort", "cannot be combined with cursor")
+		f.Page, f.Sort = 1, nil
+	}
+
+	ValidateFilters(v, f.Filters, f.sortColumns())
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied search term.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,274 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrParentTrashed = errors.New("the task's parent is in the trash")
+
+// trashRoot returns the SQL condition restricting the trashed tasks of alias
+// to those whose parent isn't trashed too. Subtasks trashed along with their
+// parent are restored and purged with it.
+func trashRoot(alias string) string {
+	return alias + `.deleted_at IS NOT NULL AND NOT EXISTS (SELECT 1 FROM tasks p WHERE p.id = ` + alias + `.parent_id AND p.deleted_at IS NOT NULL)`
+}
+
+// GetTrash lists the trashed tasks userID can see, most recently trashed
+// first.
+func (t *tasksModel) GetTrash(userID int, filters Filters) ([]*Task, Metadata, error) {
+	stmt := `
+SELECT count(*) OVER(), ` + taskColumns("$1") + `
+FROM tasks t
+WHERE ` + trashRoot("t") + ` AND ` + taskScope("t", "$1") + `
+ORDER BY t.deleted_at DESC, t.id DESC
+LIMIT $2 OFFSET $3`
+
+	rows, err := t.DB.Query(context.Background(), stmt, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	tasks := []*Task{}
+
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(append([]any{&totalRecords}, task.scanFields()...)...); err != nil {
+			return nil, Metadata{}, err
+		}
+		task.afterScan()
+
+		tasks = append(tasks, &task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return tasks, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Restore takes a task out of the trash on behalf of userID, together with
+// the subtasks trashed along with it. A subtask can't come back before its
+// parent. The restored tasks go to the end of their board column, and their
+// status and custom fields are fitted to the workflow and fields their
+// project has now.
+func (t *tasksModel) Restore(id, userID int) (*Task, error) {
+	var task Task
+
+	err := pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		var current struct {
+			projectID     *int
+			ownerID       int
+			deletedAt     time.Time
+			parentTrashed bool
+		}
+
+		stmt := `
+SELECT t.project_id, t.user_id, t.deleted_at, EXISTS (SELECT 1 FROM tasks p WHERE p.id = t.parent_id AND p.deleted_at IS NOT NULL)
+FROM tasks t
+WHERE t.id = $1 AND t.deleted_at IS NOT NULL AND ` + taskWriteScope("t", "$2") + `
+FOR UPDATE`
+
+		err := tx.QueryRow(context.Background(), stmt, id, userID).Scan(&current.projectID, &current.ownerID, &current.deletedAt, &current.parentTrashed)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		if current.parentTrashed {
+			return ErrParentTrashed
+		}
+
+		stmt = `
+WITH RECURSIVE restored AS (
+  SELECT id FROM tasks WHERE id = $1
+  UNION ALL
+  SELECT c.id FROM tasks c INNER JOIN restored r ON c.parent_id = r.id WHERE c.deleted_at = $2
+)
+SELECT id FROM restored`
+
+		rows, err := tx.Query(context.Background(), stmt, id, current.deletedAt)
+		if err != nil {
+			return err
+		}
+
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		if err := lockBoard(tx, current.projectID, current.ownerID); err != nil {
+			return err
+		}
+
+		// the workflow and the fields may have changed in the meantime
+		workflow, err := workflowFor(tx, current.projectID, current.ownerID)
+		if err != nil {
+			return err
+		}
+		if err := fitStatuses(tx, workflow, ids); err != nil {
+			return err
+		}
+		if err := fitCustomFields(tx, ids); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(context.Background(), `UPDATE tasks SET deleted_at = NULL, version = version + 1 WHERE id = ANY($1) RETURNING id, status`, ids)
+		if err != nil {
+			return err
+		}
+
+		type restoredTask struct {
+			ID     int
+			Status TaskStatus
+		}
+
+		restored, err := pgx.CollectRows(rows, pgx.RowToStructByPos[restoredTask])
+		if err != nil {
+			return err
+		}
+
+		for _, r := range restored {
+			position, err := positionBetween(tx, current.projectID, current.ownerID, r.Status, r.ID, nil, nil)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(context.Background(), `UPDATE tasks SET position = $1, version = version + 1 WHERE id = $2`, position, r.ID); err != nil {
+				return err
+			}
+		}
+
+		// assignees who left the project meanwhile lose the tasks
+		stmt = `UPDATE tasks t SET assignee_id = NULL, version = t.version + 1 WHERE t.id = ANY($1) AND t.assignee_id IS NOT NULL AND NOT ` + taskReadableBy("t", "t.assignee_id")
+		if _, err := tx.Exec(context.Background(), stmt, ids); err != nil {
+			return err
+		}
+
+		if err := recordTaskEvents(tx, ids, userID, TaskRestored, nil); err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(context.Background(), `SELECT `+taskColumns("$2")+` FROM tasks t WHERE t.id = $1`, id, userID).Scan(task.scanFields()...)
+		if err != nil {
+			return err
+		}
+		task.afterScan()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// DeletePermanently removes a trashed task and its subtasks for good on
+// behalf of userID. It returns the storage keys of the removed attachments,
+// for the caller to clean up.
+func (t *tasksModel) DeletePermanently(id, userID int) ([]string, error) {
+	var keys []string
+
+	err := pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		var found bool
+
+		stmt := `SELECT true FROM tasks t WHERE t.id = $1 AND t.deleted_at IS NOT NULL AND ` + taskWriteScope("t", "$2") + ` FOR UPDATE`
+		err := tx.QueryRow(context.Background(), stmt, id, userID).Scan(&found)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRecordNotFound
+			}
+			return err
+		}
+
+		subtree, err := taskSubtree(tx, id)
+		if err != nil {
+			return err
+		}
+
+		all := append(subtree, id)
+
+		rows, err := tx.Query(context.Background(), `SELECT storage_key FROM task_attachments WHERE task_id = ANY($1)`, all)
+		if err != nil {
+			return err
+		}
+
+		keys, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return err
+		}
+
+		if err := recordTaskEvents(tx, all, userID, TaskPurged, nil); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(context.Background(), `DELETE FROM tasks WHERE id = $1`, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// PurgeTrash removes up to limit tasks that have been in the trash for longer
+// than retention, with their subtasks. Rows locked by another instance are
+// skipped. It returns the storage keys of the removed attachments, for the
+// caller to clean up, and the number of trashed tasks it went through.
+func (t *tasksModel) PurgeTrash(retention time.Duration, limit int) ([]string, int, error) {
+	var keys []string
+	var purged int
+
+	err := pgx.BeginFunc(context.Background(), t.DB, func(tx pgx.Tx) error {
+		stmt := `SELECT t.id FROM tasks t WHERE ` + trashRoot("t") + ` AND t.deleted_at < $1 ORDER BY t.deleted_at LIMIT $2 FOR UPDATE SKIP LOCKED`
+
+		rows, err := tx.Query(context.Background(), stmt, time.Now().Add(-retention), limit)
+		if err != nil {
+			return err
+		}
+
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		purged = len(ids)
+
+		subtree, err := taskSubtree(tx, ids...)
+		if err != nil {
+			return err
+		}
+		all := append(subtree, ids...)
+
+		rows, err = tx.Query(context.Background(), `SELECT storage_key FROM task_attachments WHERE task_id = ANY($1)`, all)
+		if err != nil {
+			return err
+		}
+
+		keys, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return err
+		}
+
+		if err := recordTaskEvents(tx, all, 0, TaskPurged, nil); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(context.Background(), `DELETE FROM tasks WHERE id = ANY($1)`, ids)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return keys, purged, nil
+}
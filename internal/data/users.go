@@ -20,13 +20,16 @@ var ErrDuplicateEmail = errors.New("duplicate email")
 var AnonymousUser = &User{}
 
 type User struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	Activated bool      `json:"activated"`
-	CreatedAt time.Time `json:"created_at"`
-	Version   int       `json:"-"`
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Email     string   `json:"email"`
+	Password  password `json:"-"`
+	Activated bool     `json:"activated"`
+	// AutoArchiveDays archives the user's tasks once they have been done for
+	// that many days, 0 turns it off.
+	AutoArchiveDays int       `json:"auto_archive_days"`
+	CreatedAt       time.Time `json:"created_at"`
+	Version         int       `json:"-"`
 }
 
 func (u *User) IsAnonymous() bool {
@@ -90,7 +93,7 @@ RETURNING id, created_at, version`
 
 func (u usersModel) GetByEmail(email string) (*User, error) {
 	stmt := `
-SELECT id, created_at, name, email, password_hash, activated, version
+SELECT id, created_at, name, email, password_hash, activated, auto_archive_days, version
 FROM users
 WHERE email = $1`
 
@@ -101,6 +104,33 @@ WHERE email = $1`
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.AutoArchiveDays,
+		&user.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u usersModel) Get(id int) (*User, error) {
+	stmt := `
+SELECT id, created_at, name, email, password_hash, activated, auto_archive_days, version
+FROM users
+WHERE id = $1`
+
+	var user User
+	err := u.DB.QueryRow(context.Background(), stmt, id).Scan(&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.AutoArchiveDays,
 		&user.Version)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -116,8 +146,8 @@ WHERE email = $1`
 func (u usersModel) Update(user *User) error {
 	stmt := `
 UPDATE users
-SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-WHERE id = $5 AND version = $6
+SET name = $1, email = $2, password_hash = $3, activated = $4, auto_archive_days = $5, version = version + 1
+WHERE id = $6 AND version = $7
 RETURNING version`
 
 	args := []any{
@@ -125,6 +155,7 @@ RETURNING version`
 		user.Email,
 		user.Password.hash,
 		user.Activated,
+		user.AutoArchiveDays,
 		user.ID,
 		user.Version,
 	}
@@ -151,7 +182,7 @@ RETURNING version`
 func (u usersModel) GetForToken(token, scope string) (*User, error) {
 	hash := sha256.Sum256([]byte(token))
 	stmt := `
-      SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+      SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.auto_archive_days, users.version
       FROM users
       INNER JOIN tokens
       ON users.id = tokens.user_id
@@ -160,7 +191,7 @@ func (u usersModel) GetForToken(token, scope string) (*User, error) {
       AND tokens.expiry > $3`
 
 	var user User
-	err := u.DB.QueryRow(context.Background(), stmt, hash[:], scope, time.Now()).Scan(&user.ID, &user.CreatedAt, &user.Name, &user.Email, &user.Password.hash, &user.Activated, &user.Version)
+	err := u.DB.QueryRow(context.Background(), stmt, hash[:], scope, time.Now()).Scan(&user.ID, &user.CreatedAt, &user.Name, &user.Email, &user.Password.hash, &user.Activated, &user.AutoArchiveDays, &user.Version)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrRecordNotFound
@@ -189,6 +220,9 @@ func ValidateUser(v *validator.Validator, user *User) {
 
 	ValidateEmail(v, user.Email)
 
+	v.Check(user.AutoArchiveDays >= 0, "auto_archive_days", "must not be negative")
+	v.Check(user.AutoArchiveDays <= 3650, "auto_archive_days", "must not be more than 3650 days")
+
 	if user.Password.plainText != nil {
 		ValidatePasswordPlaintext(v, *user.Password.plainText)
 	}
@@ -0,0 +1,347 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+var (
+	ErrUnknownStatus        = errors.New("status is not a state of the task's workflow")
+	ErrTransitionNotAllowed = errors.New("the workflow doesn't allow this status change")
+)
+
+// WorkflowInUseError is returned when a workflow change would remove states
+// that tasks are still in.
+type WorkflowInUseError struct {
+	Statuses []TaskStatus
+}
+
+func (e *WorkflowInUseError) Error() string {
+	return fmt.Sprintf("workflow states %v are still in use", e.Statuses)
+}
+
+const MaxWorkflowStates = 20
+
+var workflowStateRX = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,31}$`)
+
+type WorkflowState struct {
+	Name       TaskStatus `json:"name"`
+	IsTerminal bool       `json:"is_terminal"`
+}
+
+type WorkflowTransition struct {
+	From TaskStatus `json:"from"`
+	To   TaskStatus `json:"to"`
+}
+
+// Workflow lists the statuses the tasks of a board can take, in board order.
+// New tasks start in the first state and tasks in a terminal state count as
+// done. Transitions restrict the status changes, an empty list allows any.
+type Workflow struct {
+	ProjectID   *int                 `json:"project_id"`
+	UserID      int                  `json:"-"`
+	IsDefault   bool                 `json:"is_default"`
+	States      []WorkflowState      `json:"states"`
+	Transitions []WorkflowTransition `json:"transitions"`
+}
+
+// DefaultWorkflow is the workflow of boards that don't define their own.
+func DefaultWorkflow(projectID *int, userID int) *Workflow {
+	return &Workflow{
+		ProjectID: projectID,
+		UserID:    userID,
+		IsDefault: true,
+		States: []WorkflowState{
+			{Name: taskStatusTodo},
+			{Name: taskStatusInProgress},
+			{Name: taskStatusDone, IsTerminal: true},
+		},
+		Transitions: []WorkflowTransition{},
+	}
+}
+
+func (wf *Workflow) state(status TaskStatus) (WorkflowState, bool) {
+	for _, state := range wf.States {
+		if state.Name == status {
+			return state, true
+		}
+	}
+	return WorkflowState{}, false
+}
+
+func (wf *Workflow) HasStatus(status TaskStatus) bool {
+	_, ok := wf.state(status)
+	return ok
+}
+
+func (wf *Workflow) IsTerminal(status TaskStatus) bool {
+	state, ok := wf.state(status)
+	return ok && state.IsTerminal
+}
+
+// InitialStatus is the status new tasks start in.
+func (wf *Workflow) InitialStatus() TaskStatus {
+	return wf.States[0].Name
+}
+
+// Allows reports whether a task can go from one status to another.
+func (wf *Workflow) Allows(from, to TaskStatus) bool {
+	if from == to || len(wf.Transitions) == 0 {
+		return true
+	}
+	return slices.Contains(wf.Transitions, WorkflowTransition{From: from, To: to})
+}
+
+func (wf *Workflow) statuses() []string {
+	statuses := make([]string, len(wf.States))
+	for i, state := range wf.States {
+		statuses[i] = string(state.Name)
+	}
+	return statuses
+}
+
+// workflowState returns the SQL subquery reading column from the state a task
+// of alias is in, NULL when its board uses the default workflow.
+func workflowState(alias, column string) string {
+	return `(SELECT s.` + column + ` FROM workflows w INNER JOIN workflow_states s ON s.workflow_id = w.id
+WHERE ` + inBoard("w", alias+".project_id", alias+".user_id") + ` AND s.name = ` + alias + `.status)`
+}
+
+// workflowFor loads the workflow of the board of projectID, or of userID's
+// tasks outside of any project when projectID is nil.
+func workflowFor(db dbtx, projectID *int, userID int) (*Workflow, error) {
+	var id int
+
+	err := db.QueryRow(context.Background(), `SELECT w.id FROM workflows w WHERE `+inBoard("w", "$1", "$2"), projectID, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DefaultWorkflow(projectID, userID), nil
+		}
+		return nil, err
+	}
+
+	wf := &Workflow{ProjectID: projectID, UserID: userID}
+
+	rows, err := db.Query(context.Background(), `SELECT name, is_terminal FROM workflow_states WHERE workflow_id = $1 ORDER BY position`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	wf.States, err = pgx.CollectRows(rows, pgx.RowToStructByPos[WorkflowState])
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(context.Background(), `SELECT from_status, to_status FROM workflow_transitions WHERE workflow_id = $1 ORDER BY from_status, to_status`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	wf.Transitions, err = pgx.CollectRows(rows, pgx.RowToStructByPos[WorkflowTransition])
+	if err != nil {
+		return nil, err
+	}
+
+	return wf, nil
+}
+
+// checkWorkflowStatus makes sure a task of the board can take status, coming
+// from the status from. An empty from skips the transition check, for tasks
+// that are new to the board.
+func checkWorkflowStatus(wf *Workflow, from, to TaskStatus) error {
+	if !wf.HasStatus(to) {
+		return ErrUnknownStatus
+	}
+	if from != "" && !wf.Allows(from, to) {
+		return ErrTransitionNotAllowed
+	}
+	return nil
+}
+
+// fitStatuses moves the tasks ids whose status isn't a state of wf to its
+// first terminal state when they were done, and to its initial state
+// otherwise. It runs before the tasks change board, while their done state is
+// still read from the old one.
+func fitStatuses(db dbtx, wf *Workflow, ids []int) error {
+	terminal := wf.InitialStatus()
+	for _, state := range wf.States {
+		if state.IsTerminal {
+			terminal = state.Name
+			break
+		}
+	}
+
+	stmt := `
+UPDATE tasks t SET status = CASE WHEN ` + taskIsDone("t") + ` THEN $2 ELSE $3 END, version = t.version + 1
+WHERE t.id = ANY($1) AND t.status <> ALL($4::text[])`
+
+	_, err := db.Exec(context.Background(), stmt, ids, terminal, wf.InitialStatus(), wf.statuses())
+	return err
+}
+
+type workflowsModel struct {
+	DB *pgxpool.Pool
+}
+
+func (wm workflowsModel) Get(projectID *int, userID int) (*Workflow, error) {
+	return workflowFor(wm.DB, projectID, userID)
+}
+
+// Replace saves wf as the workflow of its board. States that tasks are in
+// can't be removed.
+func (wm workflowsModel) Replace(wf *Workflow) error {
+	return pgx.BeginFunc(context.Background(), wm.DB, func(tx pgx.Tx) error {
+		err := checkStatusesInUse(tx, wf)
+		if err != nil {
+			return err
+		}
+
+		var id int
+
+		err = tx.QueryRow(context.Background(), `SELECT w.id FROM workflows w WHERE `+inBoard("w", "$1", "$2"), wf.ProjectID, wf.UserID).Scan(&id)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			err = tx.QueryRow(context.Background(), `INSERT INTO workflows (project_id, user_id) VALUES ($1, $2) RETURNING id`, wf.ProjectID, wf.UserID).Scan(&id)
+			if err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			// transitions go with the states they reference
+			_, err = tx.Exec(context.Background(), `DELETE FROM workflow_states WHERE workflow_id = $1`, id)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(context.Background(), `UPDATE workflows SET updated_at = now() WHERE id = $1`, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		terminal := make([]bool, len(wf.States))
+		for i, state := range wf.States {
+			terminal[i] = state.IsTerminal
+		}
+
+		stmt := `
+INSERT INTO workflow_states (workflow_id, name, position, is_terminal)
+SELECT $1, s.name, s.position - 1, s.is_terminal
+FROM unnest($2::text[], $3::boolean[]) WITH ORDINALITY AS s (name, is_terminal, position)`
+
+		_, err = tx.Exec(context.Background(), stmt, id, wf.statuses(), terminal)
+		if err != nil {
+			return err
+		}
+
+		from := make([]string, len(wf.Transitions))
+		to := make([]string, len(wf.Transitions))
+		for i, transition := range wf.Transitions {
+			from[i], to[i] = string(transition.From), string(transition.To)
+		}
+
+		stmt = `
+INSERT INTO workflow_transitions (workflow_id, from_status, to_status)
+SELECT $1, t.from_status, t.to_status
+FROM unnest($2::text[], $3::text[]) AS t (from_status, to_status)`
+
+		_, err = tx.Exec(context.Background(), stmt, id, from, to)
+		if err != nil {
+			return err
+		}
+
+		wf.IsDefault = false
+		return nil
+	})
+}
+
+// Reset brings a board back to the default workflow.
+func (wm workflowsModel) Reset(projectID *int, userID int) (*Workflow, error) {
+	wf := DefaultWorkflow(projectID, userID)
+
+	err := pgx.BeginFunc(context.Background(), wm.DB, func(tx pgx.Tx) error {
+		err := checkStatusesInUse(tx, wf)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(context.Background(), `DELETE FROM workflows w WHERE `+inBoard("w", "$1", "$2"), projectID, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return wf, nil
+}
+
+// checkStatusesInUse returns a *WorkflowInUseError when tasks of the board
+// are in a status wf doesn't have. It takes the board lock, which task writes
+// hold while they check their status against the workflow.
+func checkStatusesInUse(tx pgx.Tx, wf *Workflow) error {
+	err := lockBoard(tx, wf.ProjectID, wf.UserID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+SELECT DISTINCT t.status FROM tasks t
+WHERE ` + inBoard("t", "$1", "$2") + ` AND t.deleted_at IS NULL AND t.status <> ALL($3::text[])
+ORDER BY t.status`
+
+	rows, err := tx.Query(context.Background(), stmt, wf.ProjectID, wf.UserID, wf.statuses())
+	if err != nil {
+		return err
+	}
+
+	statuses, err := pgx.CollectRows(rows, pgx.RowTo[TaskStatus])
+	if err != nil {
+		return err
+	}
+
+	if len(statuses) > 0 {
+		return &WorkflowInUseError{Statuses: statuses}
+	}
+
+	return nil
+}
+
+// ValidateTaskStatus checks the form of a status. Whether a task can take it
+// depends on its workflow, see ValidateTask.
+func ValidateTaskStatus(v *validator.Validator, status TaskStatus) {
+	v.Check(validator.NotEmpty(string(status)), "status", "status must not be empty if set")
+	v.Check(validator.Matches(string(status), workflowStateRX), "status", "must be an upper case state name like IN_REVIEW")
+}
+
+func ValidateWorkflow(v *validator.Validator, wf *Workflow) {
+	v.Check(len(wf.States) > 0, "states", "must not be empty")
+	v.Check(len(wf.States) <= MaxWorkflowStates, "states", fmt.Sprintf("must not have more than %d states", MaxWorkflowStates))
+
+	seen := make(map[TaskStatus]bool, len(wf.States))
+	hasTerminal := false
+
+	for _, state := range wf.States {
+		v.Check(validator.Matches(string(state.Name), workflowStateRX), "states", "names must be upper case like IN_REVIEW, at most 32 characters long")
+		v.Check(!seen[state.Name], "states", "names must be unique")
+		seen[state.Name] = true
+		hasTerminal = hasTerminal || state.IsTerminal
+	}
+
+	if len(wf.States) > 0 {
+		v.Check(hasTerminal, "states", "at least one state must be terminal")
+		v.Check(!wf.States[0].IsTerminal, "states", "the first state must not be terminal")
+	}
+
+	for i, transition := range wf.Transitions {
+		v.Check(seen[transition.From] && seen[transition.To], "transitions", "must be between states of the workflow")
+		v.Check(transition.From != transition.To, "transitions", "must be between different states")
+		v.Check(!slices.Contains(wf.Transitions[:i], transition), "transitions", "must be unique")
+	}
+}
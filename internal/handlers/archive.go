@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+)
+
+// HandleArchiveTask archives a task along with its subtasks. List them back
+// with ?archived=true.
+func (t tasksHandler) HandleArchiveTask(w http.ResponseWriter, r *http.Request) {
+	t.handleArchive(w, r, t.models.Tasks.Archive)
+}
+
+func (t tasksHandler) HandleUnarchiveTask(w http.ResponseWriter, r *http.Request) {
+	t.handleArchive(w, r, t.models.Tasks.Unarchive)
+}
+
+func (t tasksHandler) handleArchive(w http.ResponseWriter, r *http.Request, change func(id, userID int) (*data.Task, error)) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	task, err := change(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"task": task})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
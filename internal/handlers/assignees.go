@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+func (t tasksHandler) HandleAssignTask(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserID int `json:"user_id"`
+	}
+
+	err := request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.UserID > 0, "user_id", "must be provided"); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	t.assign(w, r, &input.UserID)
+}
+
+func (t tasksHandler) HandleUnassignTask(w http.ResponseWriter, r *http.Request) {
+	t.assign(w, r, nil)
+}
+
+func (t tasksHandler) assign(w http.ResponseWriter, r *http.Request, assigneeID *int) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	previous, err := t.models.Tasks.Assign(id, user.ID, assigneeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		case errors.Is(err, data.ErrAssigneeNotMember):
+			v := validator.New()
+			v.AddError("user_id", "the assignee must be a member of the task's project")
+			t.error.FaildErrorResponse(w, r, v.Errors)
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	task, err := t.models.Tasks.GetByID(id, user.ID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	isNewAssignee := assigneeID != nil && (previous == nil || *previous != *assigneeID)
+	if isNewAssignee && *assigneeID != user.ID {
+		t.notifyAssignee(user, *assigneeID, task)
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"task": task})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// notifyAssignee emails the user the task was assigned to in the background.
+// The task may have been reassigned since, so the assignee is given apart.
+func (t tasksHandler) notifyAssignee(assigner *data.User, assigneeID int, task *data.Task) {
+	t.background(func() {
+		assignee, err := t.models.Users.Get(assigneeID)
+		if err != nil {
+			t.error.Logger.Error(err.Error())
+			return
+		}
+
+		data := map[string]any{
+			"assigneeName": assignee.Name,
+			"assignerName": assigner.Name,
+			"taskID":       task.ID,
+			"taskTitle":    task.Title,
+			"dueAt":        "",
+		}
+		if task.DueAt != nil {
+			data["dueAt"] = task.DueAt.Format(time.RFC1123)
+		}
+
+		err = t.mailer.Send(assignee.Email, "task_assigned.tmpl", data)
+		if err != nil {
+			t.error.Logger.Error(err.Error())
+		}
+	})
+}
+
+func (t tasksHandler) HandleGetAssignedTasks(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filters := readTaskFilters(r.URL.Query(), v)
+
+	user := ctx.ContextGetUser(r)
+	filters.AssigneeID = &user.ID
+
+	if err := t.loadFilterFields(&filters); err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateTaskFilters(v, filters); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	t.writeTasks(w, r, filters)
+}
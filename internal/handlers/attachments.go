@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/storage"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+const (
+	// attachmentTransferTimeout replaces the server's short read and write
+	// deadlines while an attachment is uploaded or downloaded.
+	attachmentTransferTimeout = 10 * time.Minute
+	// multipartOverhead leaves room for the multipart boundaries and part
+	// headers around the file itself.
+	multipartOverhead = 64 << 10
+	sniffLen          = 512
+)
+
+type attachmentsHandler struct {
+	models     data.Models
+	error      response.ErrorResponse
+	storage    storage.Store
+	background func(fn func())
+	// maxFileSize and userQuota are in bytes.
+	maxFileSize int64
+	userQuota   int64
+}
+
+func extendDeadlines(w http.ResponseWriter) error {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(attachmentTransferTimeout)
+
+	if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// removeBlobs deletes blobs in the background once their metadata is gone.
+// Failures are only logged, they leave an orphaned file behind at worst.
+func removeBlobs(store storage.Store, background func(fn func()), logger *slog.Logger, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	background(func() {
+		for _, key := range keys {
+			if err := store.Delete(context.Background(), key); err != nil {
+				logger.Error(err.Error(), "storage_key", key)
+			}
+		}
+	})
+}
+
+func (a attachmentsHandler) HandleGetAttachments(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		a.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	attachments, err := a.models.Attachments.GetAllForTask(id)
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"attachments": attachments})
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleUploadAttachment streams the "file" part of a multipart/form-data
+// body straight into storage, hashing it on the way, so uploads are never
+// buffered in memory.
+func (a attachmentsHandler) HandleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		a.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	err = extendDeadlines(w)
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	usage, err := a.models.Attachments.Usage(user.ID)
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	remaining := a.userQuota - usage
+	if remaining <= 0 {
+		a.error.ContentTooLargeResponse(w, r, "your attachment storage quota is used up")
+		return
+	}
+
+	limit := min(a.maxFileSize, remaining)
+
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		a.error.BadRequestResponse(w, r, errors.New("body must be multipart/form-data"))
+		return
+	}
+
+	var file io.Reader
+	var filename string
+
+	for file == nil {
+		part, err := mr.NextPart()
+		if err != nil {
+			switch {
+			case errors.Is(err, io.EOF):
+				a.error.FaildErrorResponse(w, r, map[string]string{"file": "must be provided"})
+			default:
+				a.uploadErrorResponse(w, r, err, limit)
+			}
+			return
+		}
+
+		if part.FormName() == "file" && part.FileName() != "" {
+			file = part
+			filename = part.FileName()
+		}
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		a.uploadErrorResponse(w, r, err, limit)
+		return
+	}
+	head = head[:n]
+
+	attachment := &data.Attachment{
+		TaskID:      id,
+		UserID:      user.ID,
+		Filename:    filename,
+		ContentType: http.DetectContentType(head),
+	}
+
+	v := validator.New()
+
+	if data.ValidateAttachment(v, attachment); !v.Valid() {
+		a.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	attachment.StorageKey, err = data.NewAttachmentKey(id)
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	hash := sha256.New()
+	body := io.TeeReader(io.LimitReader(io.MultiReader(bytes.NewReader(head), file), limit+1), hash)
+
+	attachment.Size, err = a.storage.Put(r.Context(), attachment.StorageKey, body)
+	if err == nil && attachment.Size > limit {
+		err = &http.MaxBytesError{Limit: limit}
+	}
+	if err != nil {
+		a.discard(attachment.StorageKey)
+		a.uploadErrorResponse(w, r, err, limit)
+		return
+	}
+
+	attachment.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	err = a.models.Attachments.Insert(attachment, a.userQuota)
+	if err != nil {
+		a.discard(attachment.StorageKey)
+		switch {
+		case errors.Is(err, data.ErrQuotaExceeded):
+			a.error.ContentTooLargeResponse(w, r, "this file would exceed your attachment storage quota")
+		default:
+			a.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Add("location", fmt.Sprintf("api/v1/tasks/%d/attachments/%d", id, attachment.ID))
+	err = response.JSONWithHeaders(w, http.StatusCreated, response.Envelope{"attachment": attachment}, w.Header())
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (a attachmentsHandler) discard(key string) {
+	removeBlobs(a.storage, a.background, a.error.Logger, []string{key})
+}
+
+func (a attachmentsHandler) uploadErrorResponse(w http.ResponseWriter, r *http.Request, err error, limit int64) {
+	var maxBytesError *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesError) && limit < a.maxFileSize:
+		a.error.ContentTooLargeResponse(w, r, "this file would exceed your attachment storage quota")
+	case errors.As(err, &maxBytesError):
+		a.error.ContentTooLargeResponse(w, r, fmt.Sprintf("file must not be larger than %d bytes", a.maxFileSize))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		a.error.BadRequestResponse(w, r, errors.New("body contains a malformed multipart form"))
+	default:
+		a.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (a attachmentsHandler) HandleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		a.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	attachmentID, err := readIntParam(r, "attachmentID")
+	if err != nil {
+		a.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	attachment, err := a.models.Attachments.Get(attachmentID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.error.NotFoundResponse(w, r, "attachment not found")
+		default:
+			a.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	blob, err := a.storage.Get(r.Context(), attachment.StorageKey)
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+		return
+	}
+	defer blob.Close()
+
+	err = extendDeadlines(w)
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	// Always serve as a download with the sniffed type, so an uploaded HTML
+	// file can't run in the API's origin.
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		a.error.LogError(r, err)
+	}
+}
+
+func (a attachmentsHandler) HandleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		a.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	attachmentID, err := readIntParam(r, "attachmentID")
+	if err != nil {
+		a.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	key, err := a.models.Attachments.Delete(attachmentID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.error.NotFoundResponse(w, r, "attachment not found")
+		default:
+			a.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	a.discard(key)
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "attachment deleted successfully"})
+	if err != nil {
+		a.error.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// HandleGetBoard returns the tasks of ?project=, or the current user's tasks
+// outside of any project, grouped by status in board order.
+func (t tasksHandler) HandleGetBoard(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := t.readProjectQuery(w, r)
+	if !ok {
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	columns, err := t.models.Tasks.GetBoard(user.ID, projectID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"project_id": projectID, "columns": columns})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleMoveTaskOnBoard changes a task's status and its place in the status
+// column at once. after_id and before_id name the tasks it is dropped
+// between; without either it goes to the end of the column.
+func (t tasksHandler) HandleMoveTaskOnBoard(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		Status   *string `json:"status"`
+		AfterID  *int    `json:"after_id"`
+		BeforeID *int    `json:"before_id"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Status != nil, "status", "must be provided")
+	status := data.GetTaskStatus(input.Status)
+	if input.Status != nil {
+		data.ValidateTaskStatus(v, status)
+	}
+	if !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	task, err := t.models.Tasks.MoveOnBoard(id, user.ID, status, input.AfterID, input.BeforeID)
+	if err != nil {
+		if t.blockedResponse(w, r, err) {
+			return
+		}
+		if key, msg, ok := taskRelationError(err); ok {
+			v.AddError(key, msg)
+			t.error.FaildErrorResponse(w, r, v.Errors)
+			return
+		}
+
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		case errors.Is(err, data.ErrBoardNeighbour):
+			v.AddError("after_id", "after_id and before_id must be tasks of the target column, in board order")
+			t.error.FaildErrorResponse(w, r, v.Errors)
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"task": task})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// Actions of the bulk endpoint.
+const (
+	bulkUpdate = "update"
+	bulkDelete = "delete"
+)
+
+// HandleBulkTasks updates or trashes many tasks at once. The tasks are given
+// either by ids or by a filter, written as the query string of the task
+// listing (e.g. "status=todo&project=3"). The batch runs in one transaction
+// and the response reports the outcome for each task: one failing doesn't
+// keep the others from being changed.
+func (t tasksHandler) HandleBulkTasks(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Action  string  `json:"action"`
+		IDs     []int   `json:"ids"`
+		Filter  *string `json:"filter"`
+		Changes struct {
+			Status   *string `json:"status"`
+			Priority *string `json:"priority"`
+			// Labels replaces the labels, AddLabels and RemoveLabels edit them.
+			Labels       []string `json:"labels"`
+			AddLabels    []string `json:"add_labels"`
+			RemoveLabels []string `json:"remove_labels"`
+			// ProjectID moves the tasks, null moves them to the inbox.
+			ProjectID json.RawMessage `json:"project_id"`
+		} `json:"changes"`
+	}
+
+	err := request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	changes := input.Changes
+
+	var projectID *int
+	moveProject := changes.ProjectID != nil
+	if moveProject {
+		if err := json.Unmarshal(changes.ProjectID, &projectID); err != nil {
+			t.error.BadRequestResponse(w, r, errors.New("body contains incorrect JSON type for field \"project_id\""))
+			return
+		}
+	}
+
+	v := validator.New()
+
+	v.Check(validator.PremittedValues(input.Action, []string{bulkUpdate, bulkDelete}), "action", "must be one of `update`, `delete`")
+	v.Check((input.IDs == nil) != (input.Filter == nil), "ids", "give either ids or a filter")
+	v.Check(len(input.IDs) <= data.MaxBulkTasks, "ids", fmt.Sprintf("must not list more than %d tasks", data.MaxBulkTasks))
+	for _, id := range input.IDs {
+		v.Check(id > 0, "ids", "must only contain task ids")
+	}
+	if projectID != nil {
+		v.Check(*projectID > 0, "project_id", "must be a project id or null")
+	}
+
+	hasChanges := changes.Status != nil || changes.Priority != nil || changes.Labels != nil ||
+		changes.AddLabels != nil || changes.RemoveLabels != nil || moveProject
+	switch input.Action {
+	case bulkUpdate:
+		v.Check(hasChanges, "changes", "must change at least one field")
+		v.Check(changes.Labels == nil || (changes.AddLabels == nil && changes.RemoveLabels == nil), "labels", "can't be combined with add_labels or remove_labels")
+	case bulkDelete:
+		v.Check(!hasChanges, "changes", "must be empty to delete tasks")
+	}
+
+	if !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	ids := input.IDs
+	if input.Filter != nil {
+		var ok bool
+		ids, ok = t.bulkFilterIDs(w, r, *input.Filter)
+		if !ok {
+			return
+		}
+	}
+
+	var results []data.BulkResult
+
+	switch input.Action {
+	case bulkUpdate:
+		results, err = t.models.Tasks.BulkUpdate(ids, user.ID, func(task *data.Task) {
+			if changes.Status != nil {
+				task.Status = data.GetTaskStatus(changes.Status)
+			}
+			if changes.Priority != nil {
+				task.Priority = data.GetTaskPriority(changes.Priority)
+			}
+			if changes.Labels != nil {
+				task.Labels = data.NormalizeLabelNames(changes.Labels)
+			}
+			if changes.AddLabels != nil {
+				task.Labels = data.NormalizeLabelNames(append(task.Labels, changes.AddLabels...))
+			}
+			if changes.RemoveLabels != nil {
+				task.Labels = slices.DeleteFunc(task.Labels, func(label string) bool {
+					return slices.ContainsFunc(changes.RemoveLabels, func(name string) bool {
+						return strings.EqualFold(strings.TrimSpace(name), label)
+					})
+				})
+			}
+			if moveProject {
+				task.ProjectID = projectID
+			}
+		})
+	case bulkDelete:
+		results, err = t.models.Tasks.BulkDelete(ids, user.ID)
+	}
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	items := make([]response.Envelope, 0, len(results))
+	failed := 0
+
+	for _, result := range results {
+		item := response.Envelope{"id": result.ID, "ok": result.Err == nil}
+		if result.Err != nil {
+			failed++
+			bulkResultError(item, result.Err)
+		} else if result.Task != nil {
+			item["task"] = result.Task
+		}
+
+		items = append(items, item)
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{
+		"results":   items,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+	})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// bulkFilterIDs resolves the filter of a bulk request to the ids of the
+// matching tasks. It answers with 422 and reports false when the filter is
+// invalid or matches more tasks than a batch can hold.
+func (t tasksHandler) bulkFilterIDs(w http.ResponseWriter, r *http.Request, filter string) ([]int, bool) {
+	v := validator.New()
+
+	qs, err := url.ParseQuery(filter)
+	if err != nil {
+		v.AddError("filter", "must be a query string")
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return nil, false
+	}
+
+	// the whole match is one batch
+	for _, param := range []string{"page", "page_size", "cursor", "sort"} {
+		v.Check(!qs.Has(param), "filter", fmt.Sprintf("can't use %q", param))
+	}
+
+	filters := readTaskFilters(qs, v)
+	filters.Page, filters.PageSize = 1, data.MaxBulkTasks
+
+	if err := t.loadFilterFields(&filters); err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	if data.ValidateTaskFilters(v, filters); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return nil, false
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	tasks, metadata, err := t.models.Tasks.GetAll(user.ID, filters)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	if metadata.TotalRecords > data.MaxBulkTasks {
+		v.AddError("filter", fmt.Sprintf("matches %d tasks, a batch can't have more than %d", metadata.TotalRecords, data.MaxBulkTasks))
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return nil, false
+	}
+
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	return ids, true
+}
+
+// bulkResultError describes the error of a task in a bulk result the way the
+// single task endpoints report it.
+func bulkResultError(item response.Envelope, err error) {
+	var invalid *data.TaskValidationError
+	var blocked *data.BlockedError
+
+	switch {
+	case errors.As(err, &invalid):
+		item["errors"] = invalid.Errors
+	case errors.As(err, &blocked):
+		item["error"] = "the task can't leave its initial status until the tasks blocking it are done"
+		item["blocked_by"] = blocked.BlockerIDs
+	case errors.Is(err, data.ErrRecordNotFound):
+		item["error"] = "task not found"
+	case errors.Is(err, data.ErrEditConflict):
+		item["error"] = "the task was changed while the batch ran, try again"
+	default:
+		if key, msg, ok := taskRelationError(err); ok {
+			item["errors"] = map[string]string{key: msg}
+			return
+		}
+		item["error"] = err.Error()
+	}
+}
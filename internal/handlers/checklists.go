@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+func (t tasksHandler) HandleGetChecklist(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	items, err := t.models.Checklists.GetAll(id)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	var progress *data.ChecklistProgress
+	if len(items) > 0 {
+		progress = &data.ChecklistProgress{Total: len(items)}
+		for _, item := range items {
+			if item.Checked {
+				progress.Checked++
+			}
+		}
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"items": items, "checklist_progress": progress})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleCreateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		Text     string `json:"text"`
+		Checked  bool   `json:"checked"`
+		Position *int   `json:"position"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	item := &data.ChecklistItem{
+		TaskID:  id,
+		Text:    input.Text,
+		Checked: input.Checked,
+	}
+
+	v := validator.New()
+
+	data.ValidateChecklistItem(v, item)
+	if input.Position != nil {
+		v.Check(*input.Position >= 0, "position", "must not be negative")
+	}
+	if !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = t.models.Checklists.Insert(item, input.Position)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		case errors.Is(err, data.ErrChecklistFull):
+			v.AddError("text", fmt.Sprintf("a checklist can't have more than %d items", data.MaxChecklistItems))
+			t.error.FaildErrorResponse(w, r, v.Errors)
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Add("location", fmt.Sprintf("api/v1/tasks/%d/checklist/%d", id, item.ID))
+	err = response.JSONWithHeaders(w, http.StatusCreated, response.Envelope{"item": item}, w.Header())
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleUpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	itemID, err := readIntParam(r, "itemID")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	item, err := t.models.Checklists.Get(itemID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "checklist item not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Text    *string `json:"text"`
+		Checked *bool   `json:"checked"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Text != nil {
+		item.Text = *input.Text
+	}
+	if input.Checked != nil {
+		item.Checked = *input.Checked
+	}
+
+	v := validator.New()
+
+	if data.ValidateChecklistItem(v, item); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = t.models.Checklists.Update(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "checklist item not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"item": item})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleToggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	itemID, err := readIntParam(r, "itemID")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	item, err := t.models.Checklists.Toggle(itemID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "checklist item not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"item": item})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleDeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	itemID, err := readIntParam(r, "itemID")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	err = t.models.Checklists.Delete(itemID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "checklist item not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "checklist item deleted successfully"})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleReorderChecklist takes the ids of all the task's checklist items in
+// their new order.
+func (t tasksHandler) HandleReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		ItemIDs []int `json:"item_ids"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	err = t.models.Checklists.Reorder(id, input.ItemIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		case errors.Is(err, data.ErrChecklistOrder):
+			v.AddError("item_ids", "must list every checklist item of the task exactly once")
+			t.error.FaildErrorResponse(w, r, v.Errors)
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	items, err := t.models.Checklists.GetAll(id)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"items": items})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+type commentsHandler struct {
+	models data.Models
+	error  response.ErrorResponse
+}
+
+// readTask loads the task in the {id} URL parameter, making sure the current
+// user can read it. It writes the error response and returns false otherwise.
+func (c commentsHandler) readTask(w http.ResponseWriter, r *http.Request) (*data.Task, bool) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		c.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return nil, false
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	task, err := c.models.Tasks.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			c.error.NotFoundResponse(w, r, "task not found")
+		default:
+			c.error.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return task, true
+}
+
+func (c commentsHandler) HandleGetComments(w http.ResponseWriter, r *http.Request) {
+	task, ok := c.readTask(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:     readInt(qs, "page", 1, v),
+		PageSize: readInt(qs, "page_size", 50, v),
+	}
+
+	if data.ValidateFilters(v, filters, nil); !v.Valid() {
+		c.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	comments, metadata, err := c.models.Comments.GetAllForTask(task.ID, filters)
+	if err != nil {
+		c.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"comments": comments, "metadata": metadata})
+	if err != nil {
+		c.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (c commentsHandler) HandleCreateComment(w http.ResponseWriter, r *http.Request) {
+	task, ok := c.readTask(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err := request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		c.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	comment := &data.Comment{
+		TaskID: task.ID,
+		UserID: user.ID,
+		Body:   input.Body,
+	}
+
+	v := validator.New()
+
+	if data.ValidateComment(v, comment); !v.Valid() {
+		c.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = c.models.Comments.Insert(comment)
+	if err != nil {
+		c.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("location", fmt.Sprintf("api/v1/tasks/%d/comments/%d", task.ID, comment.ID))
+	err = response.JSONWithHeaders(w, http.StatusCreated, response.Envelope{"comment": comment}, w.Header())
+	if err != nil {
+		c.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (c commentsHandler) HandleUpdateComment(w http.ResponseWriter, r *http.Request) {
+	task, ok := c.readTask(w, r)
+	if !ok {
+		return
+	}
+
+	commentID, err := readIntParam(r, "commentID")
+	if err != nil {
+		c.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	comment, err := c.models.Comments.Get(commentID, task.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			c.error.NotFoundResponse(w, r, "comment not found")
+		default:
+			c.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	if comment.UserID != user.ID {
+		c.error.NotPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		c.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	comment.Body = input.Body
+
+	v := validator.New()
+
+	if data.ValidateComment(v, comment); !v.Valid() {
+		c.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = c.models.Comments.Update(comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			c.error.NotFoundResponse(w, r, "comment not found")
+		default:
+			c.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"comment": comment})
+	if err != nil {
+		c.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (c commentsHandler) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	task, ok := c.readTask(w, r)
+	if !ok {
+		return
+	}
+
+	commentID, err := readIntParam(r, "commentID")
+	if err != nil {
+		c.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	comment, err := c.models.Comments.Get(commentID, task.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			c.error.NotFoundResponse(w, r, "comment not found")
+		default:
+			c.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	if comment.UserID != user.ID {
+		c.error.NotPermittedResponse(w, r)
+		return
+	}
+
+	err = c.models.Comments.Delete(comment.ID, task.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			c.error.NotFoundResponse(w, r, "comment not found")
+		default:
+			c.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "comment deleted successfully"})
+	if err != nil {
+		c.error.ServerErrorResponse(w, r, err)
+	}
+}
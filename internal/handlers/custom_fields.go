@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+func (p projectsHandler) HandleGetCustomFields(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	fields, err := p.models.CustomFields.GetAll(id)
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"fields": fields})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleCreateCustomField(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		Key     string   `json:"key"`
+		Name    string   `json:"name"`
+		Type    string   `json:"type"`
+		Options []string `json:"options"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		p.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	field := &data.CustomField{
+		ProjectID: id,
+		Key:       strings.TrimSpace(input.Key),
+		Name:      strings.TrimSpace(input.Name),
+		Type:      data.CustomFieldType(strings.ToLower(input.Type)),
+		Options:   normalizeFieldOptions(input.Options),
+	}
+
+	v := validator.New()
+
+	if data.ValidateCustomField(v, field); !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	fields, err := p.models.CustomFields.GetAll(id)
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+		return
+	}
+	if len(fields) >= data.MaxCustomFields {
+		v.AddError("key", fmt.Sprintf("a project can't have more than %d custom fields", data.MaxCustomFields))
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = p.models.CustomFields.Insert(field)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateField):
+			v.AddError("key", "the project already has a field with this key")
+			p.error.FaildErrorResponse(w, r, v.Errors)
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Add("location", fmt.Sprintf("api/v1/projects/%d/fields/%d", id, field.ID))
+	err = response.JSONWithHeaders(w, http.StatusCreated, response.Envelope{"field": field}, w.Header())
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleUpdateCustomField changes a field's name and options. Its key and type
+// are fixed, since task values depend on them.
+func (p projectsHandler) HandleUpdateCustomField(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	fieldID, err := readIntParam(r, "fieldID")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	field, err := p.models.CustomFields.Get(fieldID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "custom field not found")
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name    *string  `json:"name"`
+		Options []string `json:"options"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		p.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		field.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Options != nil {
+		field.Options = normalizeFieldOptions(input.Options)
+	}
+
+	v := validator.New()
+
+	if data.ValidateCustomField(v, field); !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = p.models.CustomFields.Update(field)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "custom field not found")
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"field": field})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleDeleteCustomField removes a field along with its values on the
+// project's tasks.
+func (p projectsHandler) HandleDeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	fieldID, err := readIntParam(r, "fieldID")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	err = p.models.CustomFields.Delete(fieldID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "custom field not found")
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "custom field deleted successfully"})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func normalizeFieldOptions(options []string) []string {
+	normalized := make([]string, 0, len(options))
+	for _, option := range options {
+		normalized = append(normalized, strings.TrimSpace(option))
+	}
+	return normalized
+}
+
+// setCustomFields validates the custom field values given for a task against
+// the fields of its project and merges them into the task.
+func (t tasksHandler) setCustomFields(v *validator.Validator, task *data.Task, values map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if task.ProjectID == nil {
+		v.AddError("custom_fields", "only tasks in a project have custom fields")
+		return nil
+	}
+
+	fields, err := t.models.CustomFields.GetAll(*task.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	var members []int
+
+	hasUserField := slices.ContainsFunc(fields, func(field *data.CustomField) bool {
+		_, ok := values[field.Key]
+		return ok && field.Type == data.FieldUser
+	})
+	if hasUserField {
+		projectMembers, err := t.models.Members.GetAll(*task.ProjectID)
+		if err != nil {
+			return err
+		}
+		for _, member := range projectMembers {
+			members = append(members, member.UserID)
+		}
+	}
+
+	if data.ValidateCustomFieldValues(v, fields, values, members); v.Valid() {
+		task.ApplyCustomFieldValues(values)
+	}
+
+	return nil
+}
+
+// loadFilterFields loads the custom fields the task list filters or sorts on,
+// those of the project it is listed from.
+func (t tasksHandler) loadFilterFields(filters *data.TaskFilters) error {
+	if !filters.UsesCustomFields() || filters.ProjectID == nil {
+		return nil
+	}
+
+	fields, err := t.models.CustomFields.GetAll(*filters.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	filters.CustomFields = fields
+	return nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+func (t tasksHandler) HandleGetTaskBlockers(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	blockers, err := t.models.Tasks.GetBlockers(id, user.ID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"tasks": blockers})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleAddTaskBlocker(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		TaskID int `json:"task_id"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.TaskID > 0, "task_id", "must be provided"); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	err = t.models.Tasks.AddBlocker(id, input.TaskID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrBlockerNotFound):
+			v.AddError("task_id", "blocking task not found")
+			t.error.FaildErrorResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDependencyCycle):
+			t.error.ConflictResponse(w, r, "this dependency would create a cycle")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	blockers, err := t.models.Tasks.GetBlockers(id, user.ID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"tasks": blockers})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleRemoveTaskBlocker(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	blockerID, err := readIntParam(r, "blockerID")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	err = t.models.Tasks.RemoveBlocker(id, blockerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "dependency not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "dependency removed successfully"})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleGetDependencyGraph returns the dependency graph of the current
+// user's tasks, or of a single project with ?project=.
+func (t tasksHandler) HandleGetDependencyGraph(w http.ResponseWriter, r *http.Request) {
+	user := ctx.ContextGetUser(r)
+
+	projectID, ok := t.readProjectQuery(w, r)
+	if !ok {
+		return
+	}
+
+	graph, err := t.models.Tasks.DependencyGraph(user.ID, projectID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"graph": graph})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// readProjectQuery reads the optional ?project= id of a project the current
+// user is a member of. It writes the error response and returns false when
+// the id is invalid or the project can't be seen.
+func (t tasksHandler) readProjectQuery(w http.ResponseWriter, r *http.Request) (*int, bool) {
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		return nil, true
+	}
+
+	id, err := strconv.Atoi(project)
+	if err != nil || id < 1 {
+		v := validator.New()
+		v.AddError("project", "must be a project id")
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return nil, false
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	_, err = t.models.Projects.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "project not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return &id, true
+}
+
+// blockedResponse answers with 409 Conflict, listing the unfinished blockers,
+// when err is a *data.BlockedError. It reports whether it did.
+func (t tasksHandler) blockedResponse(w http.ResponseWriter, r *http.Request, err error) bool {
+	var blocked *data.BlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+
+	t.error.ConflictResponse(w, r, map[string]any{
+		"message":    "the task can't leave its initial status until the tasks blocking it are done",
+		"blocked_by": blocked.BlockerIDs,
+	})
+
+	return true
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+)
+
+// taskETag is the entity tag of a version of a task. The progress of its
+// subtasks is part of it, as their changes don't bump the task's version.
+func taskETag(task *data.Task) string {
+	tag := strconv.Itoa(task.Version)
+	if task.Progress != nil {
+		tag += "-" + strconv.Itoa(task.Progress.Done) + "." + strconv.Itoa(task.Progress.Total)
+	}
+
+	return `"` + tag + `"`
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists etag.
+// If-None-Match compares weakly, ignoring the W/ prefix.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkIfMatch requires the request to be conditional on the current version
+// of the task, answering with 428 or 412 otherwise. It reports whether the
+// request can go on.
+func (t tasksHandler) checkIfMatch(w http.ResponseWriter, r *http.Request, task *data.Task) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		t.error.PreconditionRequiredResponse(w, r)
+		return false
+	}
+
+	if !etagMatches(header, taskETag(task), false) {
+		t.error.PreconditionFailedResponse(w, r)
+		return false
+	}
+
+	return true
+}
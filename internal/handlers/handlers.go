@@ -2,21 +2,67 @@ package handlers
 
 import (
 	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/mailer"
 	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/storage"
 )
 
 type Config struct {
 	Models data.Models
 	Error  response.ErrorResponse
+	Mailer mailer.Mailer
+	// Background runs fn in a goroutine the application waits for on
+	// shutdown.
+	Background func(fn func())
+	Storage    storage.Store
+	// MaxAttachmentSize and AttachmentQuota limit, in bytes, a single
+	// uploaded file and the total a user can upload.
+	MaxAttachmentSize int64
+	AttachmentQuota   int64
 }
 
 type Handlers struct {
-	Tasks tasksHandler
+	Tasks       tasksHandler
+	Labels      labelsHandler
+	Projects    projectsHandler
+	Comments    commentsHandler
+	Attachments attachmentsHandler
+	Workflows   workflowsHandler
 }
 
 func New(cfg Config) *Handlers {
 	return &Handlers{
 		Tasks: tasksHandler{
+			models:     cfg.Models,
+			error:      cfg.Error,
+			mailer:     cfg.Mailer,
+			background: cfg.Background,
+			storage:    cfg.Storage,
+		},
+		Labels: labelsHandler{
+			models: cfg.Models,
+			error:  cfg.Error,
+		},
+		Projects: projectsHandler{
+			models:     cfg.Models,
+			error:      cfg.Error,
+			mailer:     cfg.Mailer,
+			background: cfg.Background,
+			storage:    cfg.Storage,
+		},
+		Comments: commentsHandler{
+			models: cfg.Models,
+			error:  cfg.Error,
+		},
+		Attachments: attachmentsHandler{
+			models:      cfg.Models,
+			error:       cfg.Error,
+			storage:     cfg.Storage,
+			background:  cfg.Background,
+			maxFileSize: cfg.MaxAttachmentSize,
+			userQuota:   cfg.AttachmentQuota,
+		},
+		Workflows: workflowsHandler{
 			models: cfg.Models,
 			error:  cfg.Error,
 		},
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// HandleGetTaskHistory lists the changes made to a task, newest first.
+func (t tasksHandler) HandleGetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	filters, ok := t.readEventFilters(w, r)
+	if !ok {
+		return
+	}
+
+	events, metadata, err := t.models.TaskEvents.GetAllForTask(id, filters)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"events": events, "metadata": metadata})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleGetActivity lists the changes made to the tasks the user can see,
+// including deleted ones, and the changes they made themselves, newest first.
+func (t tasksHandler) HandleGetActivity(w http.ResponseWriter, r *http.Request) {
+	filters, ok := t.readEventFilters(w, r)
+	if !ok {
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	events, metadata, err := t.models.TaskEvents.GetFeed(user.ID, filters)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"events": events, "metadata": metadata})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) readEventFilters(w http.ResponseWriter, r *http.Request) (data.Filters, bool) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:     readInt(qs, "page", 1, v),
+		PageSize: readInt(qs, "page_size", 50, v),
+	}
+
+	if data.ValidateFilters(v, filters, nil); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return data.Filters{}, false
+	}
+
+	return filters, true
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+type labelsHandler struct {
+	models data.Models
+	error  response.ErrorResponse
+}
+
+func (l labelsHandler) HandleGetLabels(w http.ResponseWriter, r *http.Request) {
+	user := ctx.ContextGetUser(r)
+
+	labels, err := l.models.Labels.GetAll(user.ID)
+	if err != nil {
+		l.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"labels": labels})
+	if err != nil {
+		l.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (l labelsHandler) HandleCreateLabel(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name  string  `json:"name"`
+		Color *string `json:"color"`
+	}
+
+	err := request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		l.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	label := &data.Label{
+		Name:   strings.TrimSpace(input.Name),
+		Color:  "#808080",
+		UserID: user.ID,
+	}
+	if input.Color != nil {
+		label.Color = *input.Color
+	}
+
+	v := validator.New()
+
+	if data.ValidateLabel(v, label); !v.Valid() {
+		l.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = l.models.Labels.Insert(label)
+	if err != nil {
+		if errors.Is(err, data.ErrDuplicateLabel) {
+			v.AddError("name", "a label with this name already exists")
+			l.error.FaildErrorResponse(w, r, v.Errors)
+			return
+		}
+		l.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("location", fmt.Sprint("api/v1/labels/", label.ID))
+	err = response.JSONWithHeaders(w, http.StatusCreated, response.Envelope{"label": label}, w.Header())
+	if err != nil {
+		l.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (l labelsHandler) HandleGetLabelByID(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		l.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	label, err := l.models.Labels.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			l.error.NotFoundResponse(w, r, "label not found")
+		default:
+			l.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"label": label})
+	if err != nil {
+		l.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (l labelsHandler) HandleUpdateLabel(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		l.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	label, err := l.models.Labels.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			l.error.NotFoundResponse(w, r, "label not found")
+		default:
+			l.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name  *string `json:"name"`
+		Color *string `json:"color"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		l.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		label.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Color != nil {
+		label.Color = *input.Color
+	}
+
+	v := validator.New()
+
+	if data.ValidateLabel(v, label); !v.Valid() {
+		l.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = l.models.Labels.Update(label)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateLabel):
+			v.AddError("name", "a label with this name already exists")
+			l.error.FaildErrorResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			l.error.NotFoundResponse(w, r, "label not found")
+		default:
+			l.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"label": label})
+	if err != nil {
+		l.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (l labelsHandler) HandleDeleteLabel(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		l.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	err = l.models.Labels.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			l.error.NotFoundResponse(w, r, "label not found")
+		default:
+			l.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "label deleted successfully"})
+	if err != nil {
+		l.error.ServerErrorResponse(w, r, err)
+	}
+}
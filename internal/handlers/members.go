@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+func (p projectsHandler) HandleGetProjectMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	members, err := p.models.Members.GetAll(id)
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"members": members})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleUpdateProjectMember(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	userID, err := readIntParam(r, "userID")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		Role string `json:"role"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		p.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	role := data.ProjectRole(strings.ToLower(input.Role))
+
+	v := validator.New()
+
+	if data.ValidateProjectRole(v, role); !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = p.models.Members.UpdateRole(id, userID, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "member not found")
+		case errors.Is(err, data.ErrLastOwner):
+			v.AddError("role", "a project must keep at least one owner")
+			p.error.FaildErrorResponse(w, r, v.Errors)
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "member role updated successfully"})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleDeleteProjectMember removes a member. Owners can remove anyone, other
+// members can only leave the project themselves.
+func (p projectsHandler) HandleDeleteProjectMember(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	userID, err := readIntParam(r, "userID")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	if userID != user.ID {
+		role, err := p.models.Projects.Role(id, user.ID)
+		if err != nil {
+			p.error.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		if role != data.RoleOwner {
+			p.error.NotPermittedResponse(w, r)
+			return
+		}
+	}
+
+	err = p.models.Members.Delete(id, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "member not found")
+		case errors.Is(err, data.ErrLastOwner):
+			v := validator.New()
+			v.AddError("user_id", "a project must keep at least one owner")
+			p.error.FaildErrorResponse(w, r, v.Errors)
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "member removed successfully"})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleGetProjectInvitations(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	invitations, err := p.models.Members.GetInvitations(id)
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"invitations": invitations})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleCreateProjectInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		p.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	invitation := &data.ProjectInvitation{
+		ProjectID: id,
+		Email:     strings.TrimSpace(input.Email),
+		Role:      data.ProjectRole(strings.ToLower(input.Role)),
+		InvitedBy: user.ID,
+	}
+
+	v := validator.New()
+
+	data.ValidateEmail(v, invitation.Email)
+	data.ValidateProjectRole(v, invitation.Role)
+
+	if !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	project, err := p.models.Projects.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "project not found")
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = p.models.Members.Invite(invitation, invitationTTL)
+	if err != nil {
+		if errors.Is(err, data.ErrAlreadyMember) {
+			v.AddError("email", "this user is already a member of the project")
+			p.error.FaildErrorResponse(w, r, v.Errors)
+			return
+		}
+		p.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	p.background(func() {
+		data := map[string]any{
+			"invitationToken": invitation.Token,
+			"inviterName":     user.Name,
+			"projectName":     project.Name,
+			"role":            invitation.Role,
+		}
+		err := p.mailer.Send(invitation.Email, "project_invitation.tmpl", data)
+		if err != nil {
+			p.error.Logger.Error(err.Error())
+		}
+	})
+
+	err = response.JSON(w, http.StatusAccepted, response.Envelope{"invitation": invitation})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleDeleteProjectInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	invitationID, err := readIntParam(r, "invitationID")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	err = p.models.Members.DeleteInvitation(invitationID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "invitation not found")
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "invitation revoked successfully"})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleAcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		p.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.Token); !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	projectID, err := p.models.Members.Accept(input.Token, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvitationExpired):
+			v.AddError("token", "invalid or expired invitation token")
+			p.error.FaildErrorResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrInvitationEmail):
+			v.AddError("token", "this invitation was sent to a different email address")
+			p.error.FaildErrorResponse(w, r, v.Errors)
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	project, err := p.models.Projects.Get(projectID, user.ID)
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"project": project})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/mailer"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/storage"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+type projectsHandler struct {
+	models     data.Models
+	error      response.ErrorResponse
+	mailer     mailer.Mailer
+	background func(fn func())
+	storage    storage.Store
+}
+
+func (p projectsHandler) HandleGetProjects(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	archived := readBool(r.URL.Query(), "archived", v)
+	if !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	projects, err := p.models.Projects.GetAll(user.ID, archived)
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"projects": projects})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleCreateProject(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		Color       *string `json:"color"`
+	}
+
+	err := request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		p.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	project := &data.Project{
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+		Color:       "#808080",
+		UserID:      user.ID,
+	}
+	if input.Color != nil {
+		project.Color = *input.Color
+	}
+
+	v := validator.New()
+
+	if data.ValidateProject(v, project); !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = p.models.Projects.Insert(project)
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("location", fmt.Sprint("api/v1/projects/", project.ID))
+	err = response.JSONWithHeaders(w, http.StatusCreated, response.Envelope{"project": project}, w.Header())
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleGetProjectByID(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	project, err := p.models.Projects.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "project not found")
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"project": project})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (p projectsHandler) HandleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	project, err := p.models.Projects.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "project not found")
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Color       *string `json:"color"`
+		Archived    *bool   `json:"archived"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		p.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		project.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		project.Description = *input.Description
+	}
+	if input.Color != nil {
+		project.Color = *input.Color
+	}
+	if input.Archived != nil {
+		project.Archived = *input.Archived
+	}
+
+	v := validator.New()
+
+	if data.ValidateProject(v, project); !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = p.models.Projects.Update(project, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "project not found")
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"project": project})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleDeleteProject deletes a project. ?tasks=cascade moves its tasks to
+// the trash, the default ?tasks=inbox keeps them outside of any project.
+func (p projectsHandler) HandleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		p.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	mode := strings.ToLower(readString(r.URL.Query(), "tasks", data.ProjectDeleteInbox))
+
+	v := validator.New()
+
+	v.Check(validator.PremittedValues(mode, []string{data.ProjectDeleteCascade, data.ProjectDeleteInbox}), "tasks", "must be one of `cascade`, `inbox`")
+	if !v.Valid() {
+		p.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	err = p.models.Projects.Delete(id, user.ID, mode)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			p.error.NotFoundResponse(w, r, "project not found")
+		case errors.Is(err, data.ErrProjectReadOnly):
+			p.error.NotPermittedResponse(w, r)
+		default:
+			p.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "project deleted successfully"})
+	if err != nil {
+		p.error.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// HandleGetTaskOccurrences previews the next ?count= due dates of a recurring
+// task, after its current one.
+func (t tasksHandler) HandleGetTaskOccurrences(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	v := validator.New()
+
+	count := readInt(r.URL.Query(), "count", 5, v)
+	v.Check(count > 0 && count <= data.MaxOccurrencesPreview, "count", "must be between 1 and 100")
+	if !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	task, err := t.models.Tasks.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if task.Recurrence == nil {
+		v.AddError("recurrence", "task is not recurring")
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	occurrences, err := task.Occurrences(count)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"recurrence": task.Recurrence, "occurrences": occurrences})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+func (t tasksHandler) HandleGetTaskReminders(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	reminders, err := t.models.Reminders.GetAllForTask(id, user.ID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"reminders": reminders})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleCreateTaskReminder sets a reminder for the current user, either at
+// remind_at or offset_minutes before the task's due date.
+func (t tasksHandler) HandleCreateTaskReminder(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		RemindAt      *time.Time `json:"remind_at"`
+		OffsetMinutes *int       `json:"offset_minutes"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	task, err := t.models.Tasks.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	reminder := &data.Reminder{
+		TaskID:        task.ID,
+		UserID:        user.ID,
+		RemindAt:      input.RemindAt,
+		OffsetMinutes: input.OffsetMinutes,
+	}
+
+	v := validator.New()
+
+	if data.ValidateReminder(v, reminder, task); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = t.models.Reminders.Insert(reminder)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("location", fmt.Sprintf("api/v1/tasks/%d/reminders/%d", task.ID, reminder.ID))
+	err = response.JSONWithHeaders(w, http.StatusCreated, response.Envelope{"reminder": reminder}, w.Header())
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleDeleteTaskReminder(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	reminderID, err := readIntParam(r, "reminderID")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	err = t.models.Reminders.Delete(reminderID, id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "reminder not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "reminder deleted successfully"})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
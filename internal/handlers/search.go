@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// HandleSearch runs a full-text search over the tasks and comments the user
+// can read. ?type= narrows it to tasks or comments and ?project= to a
+// project.
+func (t tasksHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters := data.SearchFilters{
+		Query: strings.TrimSpace(qs.Get("q")),
+		Type:  strings.ToLower(qs.Get("type")),
+	}
+
+	if project := qs.Get("project"); project != "" {
+		id, err := strconv.Atoi(project)
+		if err != nil || id < 1 {
+			v.AddError("project", "must be a project id")
+		}
+		filters.ProjectID = &id
+	}
+
+	filters.Page = readInt(qs, "page", 1, v)
+	filters.PageSize = readInt(qs, "page_size", 20, v)
+
+	if data.ValidateSearchFilters(v, filters); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	results, metadata, err := t.models.Tasks.Search(user.ID, filters)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"results": results, "metadata": metadata})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
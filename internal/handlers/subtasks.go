@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+func (t tasksHandler) HandleGetTaskChildren(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	_, err = t.models.Tasks.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	children, err := t.models.Tasks.GetChildren(id, user.ID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"tasks": children})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleGetTaskTree(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	tree, err := t.models.Tasks.GetTree(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"task": tree})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleMoveTaskParent(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		ParentID *int `json:"parent_id"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	err = t.models.Tasks.Move(id, user.ID, input.ParentID)
+	if err != nil {
+		if key, msg, ok := taskRelationError(err); ok {
+			v := validator.New()
+			v.AddError(key, msg)
+			t.error.FaildErrorResponse(w, r, v.Errors)
+			return
+		}
+
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	task, err := t.models.Tasks.GetByID(id, user.ID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"task": task})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// taskRelationError maps the errors raised when a task points at a missing
+// or invalid parent or project to a validation error.
+func taskRelationError(err error) (string, string, bool) {
+	switch {
+	case errors.Is(err, data.ErrParentNotFound):
+		return "parent_id", "parent task not found", true
+	case errors.Is(err, data.ErrTaskCycle):
+		return "parent_id", "a task can't be moved under itself or one of its subtasks", true
+	case errors.Is(err, data.ErrTaskTooDeep):
+		return "parent_id", fmt.Sprintf("subtasks can't be nested more than %d levels deep", data.MaxTaskDepth), true
+	case errors.Is(err, data.ErrParentProject):
+		return "parent_id", "subtasks must be in the same project as their parent", true
+	case errors.Is(err, data.ErrProjectNotFound):
+		return "project_id", "project not found", true
+	case errors.Is(err, data.ErrProjectReadOnly):
+		return "project_id", "you can't add tasks to this project", true
+	case errors.Is(err, data.ErrUnknownStatus):
+		return "status", "is not a state of the task's workflow", true
+	case errors.Is(err, data.ErrTransitionNotAllowed):
+		return "status", "the task's workflow doesn't allow this status change", true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/jsonpatch"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// Media types of the patch documents PATCH accepts.
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// taskDocument holds the editable fields of a task: what PUT replaces and
+// what PATCH documents apply to.
+type taskDocument struct {
+	Title        string         `json:"title"`
+	Description  string         `json:"description"`
+	Priority     string         `json:"priority"`
+	Status       string         `json:"status"`
+	ProjectID    *int           `json:"project_id"`
+	Labels       []string       `json:"labels"`
+	StartAt      *time.Time     `json:"start_at"`
+	DueAt        *time.Time     `json:"due_at"`
+	Recurrence   *string        `json:"recurrence"`
+	CustomFields map[string]any `json:"custom_fields"`
+}
+
+// taskDocumentFields lists the keys a task document must have.
+var taskDocumentFields = []string{"title", "description", "priority", "status", "project_id", "labels", "start_at", "due_at", "recurrence", "custom_fields"}
+
+func newTaskDocument(task *data.Task) taskDocument {
+	doc := taskDocument{
+		Title:        task.Title,
+		Description:  task.Description,
+		Priority:     string(task.Priority),
+		Status:       string(task.Status),
+		ProjectID:    task.ProjectID,
+		Labels:       task.Labels,
+		StartAt:      task.StartAt,
+		DueAt:        task.DueAt,
+		Recurrence:   task.Recurrence,
+		CustomFields: task.CustomFields,
+	}
+
+	if doc.Labels == nil {
+		doc.Labels = []string{}
+	}
+	if doc.CustomFields == nil {
+		doc.CustomFields = map[string]any{}
+	}
+
+	return doc
+}
+
+// decodeTaskDocument decodes a task document. A full document must have
+// every key, the ones it lacks are returned as validation errors. Otherwise
+// missing keys clear their field, as patches remove fields set to null.
+func decodeTaskDocument(raw []byte, full bool) (taskDocument, map[string]string, error) {
+	var doc taskDocument
+
+	if err := request.DecodeJSONBytes(raw, &doc); err != nil {
+		return doc, nil, err
+	}
+	if !full {
+		return doc, nil, nil
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return doc, nil, errors.New("body must be a JSON object")
+	}
+
+	v := validator.New()
+	for _, key := range taskDocumentFields {
+		_, ok := keys[key]
+		v.Check(ok, key, "must be provided")
+	}
+	if !v.Valid() {
+		return doc, v.Errors, nil
+	}
+
+	return doc, nil, nil
+}
+
+// HandlePatchTask changes some of the editable fields of a task, given as a
+// JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) of its task
+// document. A null in a merge patch clears a field. The task document isn't
+// the task as GET returns it: it only has the keys of taskDocumentFields, in
+// lower case, e.g. "/title" rather than "/Title" in a JSON Patch.
+func (t tasksHandler) HandlePatchTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := t.readTaskToUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	var patch func(doc, patch []byte) ([]byte, error)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchType:
+		patch = jsonpatch.MergePatch
+	case jsonPatchType:
+		patch = jsonpatch.Apply
+	default:
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		t.error.UnsupportedMediaTypeResponse(w, r, "Content-Type must be "+mergePatchType+" or "+jsonPatchType)
+		return
+	}
+
+	var input json.RawMessage
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	current, err := json.Marshal(newTaskDocument(task))
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	patched, err := patch(current, input)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			t.error.ConflictResponse(w, r, err.Error())
+			return
+		}
+		t.error.FaildErrorResponse(w, r, map[string]string{"patch": patchError(err)})
+		return
+	}
+
+	doc, _, err := decodeTaskDocument(patched, false)
+	if err != nil {
+		t.error.FaildErrorResponse(w, r, map[string]string{"patch": patchError(err)})
+		return
+	}
+
+	t.replaceTask(w, r, task, doc)
+}
+
+// patchError describes a patch that doesn't apply, with the keys of the task
+// document, which differ from those of the task GET returns.
+func patchError(err error) string {
+	return err.Error() + ", the task document has the keys " + strings.Join(taskDocumentFields, ", ")
+}
+
+// replaceCustomFields makes values the custom field values of task. Values
+// left as they were aren't checked again, so a task moving to another
+// project keeps those the new project has a field for, as Update does.
+func (t tasksHandler) replaceCustomFields(v *validator.Validator, task *data.Task, values map[string]any) error {
+	changed := map[string]any{}
+	for key, value := range values {
+		if value != nil && !reflect.DeepEqual(task.CustomFields[key], value) {
+			changed[key] = value
+		}
+	}
+
+	for key := range task.CustomFields {
+		if values[key] == nil {
+			delete(task.CustomFields, key)
+		}
+	}
+
+	return t.setCustomFields(v, task, changed)
+}
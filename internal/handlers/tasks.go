@@ -1,28 +1,47 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
 	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/mailer"
 	"github.com/moutafatin/go-tasks-management-api/internal/request"
 	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/storage"
 	"github.com/moutafatin/go-tasks-management-api/internal/validator"
 )
 
 type tasksHandler struct {
-	models data.Models
-	error  response.ErrorResponse
+	models     data.Models
+	error      response.ErrorResponse
+	mailer     mailer.Mailer
+	background func(fn func())
+	storage    storage.Store
 }
 
 func (t tasksHandler) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		Priority    *string `json:"priority"`
-		Status      *string `json:"status"`
+		Title       string     `json:"title"`
+		Description string     `json:"description"`
+		Priority    *string    `json:"priority"`
+		Status      *string    `json:"status"`
+		ProjectID   *int       `json:"project_id"`
+		ParentID    *int       `json:"parent_id"`
+		Labels      []string   `json:"labels"`
+		StartAt     *time.Time `json:"start_at"`
+		DueAt       *time.Time `json:"due_at"`
+		Recurrence  *string    `json:"recurrence"`
+		// CustomFields maps custom field keys of the project to values.
+		CustomFields map[string]any `json:"custom_fields"`
 	}
 
 	err := request.DecodeJSONStrict(w, r, &input)
@@ -38,16 +57,42 @@ func (t tasksHandler) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 		Description: input.Description,
 		Priority:    data.GetTaskPriority(input.Priority),
 		Status:      data.GetTaskStatus(input.Status),
+		ProjectID:   input.ProjectID,
+		ParentID:    input.ParentID,
+		Labels:      data.NormalizeLabelNames(input.Labels),
+		StartAt:     input.StartAt,
+		DueAt:       input.DueAt,
+		Recurrence:  data.NormalizeRecurrence(input.Recurrence),
 		UserID:      user.ID,
 	}
+
+	workflow, err := t.models.Workflows.Get(task.ProjectID, task.UserID)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+	if input.Status == nil {
+		task.Status = workflow.InitialStatus()
+	}
+
 	v := validator.New()
 
-	if data.ValidateTask(v, task); !v.Valid() {
+	data.ValidateTask(v, task, workflow)
+	if err := t.setCustomFields(v, task, input.CustomFields); err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
 		t.error.FaildErrorResponse(w, r, v.Errors)
 		return
 	}
 	err = t.models.Tasks.Insert(task)
 	if err != nil {
+		if key, msg, ok := taskRelationError(err); ok {
+			v.AddError(key, msg)
+			t.error.FaildErrorResponse(w, r, v.Errors)
+			return
+		}
 		t.error.ServerErrorResponse(w, r, err)
 		return
 	}
@@ -59,18 +104,94 @@ func (t tasksHandler) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 }
 
 func (t tasksHandler) HandleGetTasks(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filters := readTaskFilters(r.URL.Query(), v)
+
+	if err := t.loadFilterFields(&filters); err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateTaskFilters(v, filters); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	t.writeTasks(w, r, filters)
+}
+
+func (t tasksHandler) HandleGetProjectTasks(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
 	user := ctx.ContextGetUser(r)
-	tasks, err := t.models.Tasks.GetAll(user.ID)
+
+	_, err = t.models.Projects.Get(id, user.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "project not found")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+
+	filters := readTaskFilters(r.URL.Query(), v)
+	filters.ProjectID, filters.Inbox = &id, false
+
+	if err := t.loadFilterFields(&filters); err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateTaskFilters(v, filters); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	t.writeTasks(w, r, filters)
+}
+
+// writeTasks responds with the current user's tasks matching filters, using
+// offset or cursor pagination as requested.
+func (t tasksHandler) writeTasks(w http.ResponseWriter, r *http.Request, filters data.TaskFilters) {
+	user := ctx.ContextGetUser(r)
+
+	if filters.UseCursor {
+		tasks, metadata, err := t.models.Tasks.GetAllByCursor(user.ID, filters)
+		if err != nil {
+			t.error.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		err = response.JSON(w, http.StatusOK, response.Envelope{"tasks": tasks, "metadata": metadata})
+		if err != nil {
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	tasks, metadata, err := t.models.Tasks.GetAll(user.ID, filters)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
 	}
 
-	err = response.JSON(w, http.StatusOK, response.Envelope{"tasks": tasks})
+	err = response.JSON(w, http.StatusOK, response.Envelope{"tasks": tasks, "metadata": metadata})
 	if err != nil {
 		t.error.ServerErrorResponse(w, r, err)
 	}
 }
 
+// HandleGetTaskByID answers with the task and its ETag, or with 304 Not
+// Modified when If-None-Match lists that ETag.
 func (t tasksHandler) HandleGetTaskByID(w http.ResponseWriter, r *http.Request) {
 	id, err := readIntParam(r, "id")
 	if err != nil {
@@ -91,24 +212,37 @@ func (t tasksHandler) HandleGetTaskByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	etag := taskETag(task)
+	w.Header().Set("ETag", etag)
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	err = response.JSON(w, http.StatusOK, response.Envelope{"task": task})
 	if err != nil {
 		t.error.ServerErrorResponse(w, r, err)
 	}
 }
 
+// HandleDeleteTask moves the task to the trash, if it is still at the
+// version the If-Match header gives.
 func (t tasksHandler) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
-	id, err := readIntParam(r, "id")
-	if err != nil {
-		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+	task, ok := t.readTaskToUpdate(w, r)
+	if !ok {
 		return
 	}
+
 	user := ctx.ContextGetUser(r)
-	err = t.models.Tasks.Delete(id, user.ID)
+
+	err := t.models.Tasks.Delete(task.ID, task.Version, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			t.error.NotFoundResponse(w, r, "task not found")
+		case errors.Is(err, data.ErrEditConflict):
+			t.error.PreconditionFailedResponse(w, r)
 		default:
 			t.error.ServerErrorResponse(w, r, err)
 		}
@@ -116,17 +250,48 @@ func (t tasksHandler) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// maybe return 201 no content, its depend
-	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "task deleted successfully"})
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "task moved to the trash"})
 	if err != nil {
 		t.error.ServerErrorResponse(w, r, err)
 	}
 }
 
+// HandleUpdateTask replaces the editable fields of a task, all of which must
+// be given. PATCH changes some of them.
 func (t tasksHandler) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := t.readTaskToUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	var input json.RawMessage
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	doc, errs, err := decodeTaskDocument(input, true)
+	if err != nil {
+		t.error.BadRequestResponse(w, r, err)
+		return
+	}
+	if errs != nil {
+		t.error.FaildErrorResponse(w, r, errs)
+		return
+	}
+
+	t.replaceTask(w, r, task, doc)
+}
+
+// readTaskToUpdate loads the task of the {id} parameter for a change, which
+// the If-Match header must make conditional on the task's current version.
+func (t tasksHandler) readTaskToUpdate(w http.ResponseWriter, r *http.Request) (*data.Task, bool) {
 	id, err := readIntParam(r, "id")
 	if err != nil {
 		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
-		return
+		return nil, false
 	}
 
 	user := ctx.ContextGetUser(r)
@@ -139,51 +304,161 @@ func (t tasksHandler) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		default:
 			t.error.ServerErrorResponse(w, r, err)
 		}
-		return
+		return nil, false
 	}
 
-	var input struct {
-		Title       *string `json:"title"`
-		Description *string `json:"description"`
-		Priority    *string `json:"priority"`
-		Status      *string `json:"status"`
+	if !t.checkIfMatch(w, r, task) {
+		return nil, false
 	}
 
-	err = request.DecodeJSONStrict(w, r, &input)
+	return task, true
+}
+
+// replaceTask saves doc as the new editable fields of task.
+func (t tasksHandler) replaceTask(w http.ResponseWriter, r *http.Request, task *data.Task, doc taskDocument) {
+	user := ctx.ContextGetUser(r)
+
+	task.Title = doc.Title
+	task.Description = doc.Description
+	task.Priority = data.GetTaskPriority(&doc.Priority)
+	task.Status = data.GetTaskStatus(&doc.Status)
+	task.ProjectID = doc.ProjectID
+	task.Labels = data.NormalizeLabelNames(doc.Labels)
+	task.StartAt = doc.StartAt
+	task.DueAt = doc.DueAt
+	task.Recurrence = data.NormalizeRecurrence(doc.Recurrence)
+
+	workflow, err := t.models.Workflows.Get(task.ProjectID, task.UserID)
 	if err != nil {
-		t.error.BadRequestResponse(w, r, err)
+		t.error.ServerErrorResponse(w, r, err)
 		return
 	}
 
-	if input.Title != nil {
-		task.Title = *input.Title
-	}
-	if input.Description != nil {
-		task.Description = *input.Description
-	}
-	if input.Priority != nil {
-		task.Priority = data.GetTaskPriority(input.Priority)
-	}
-	if input.Status != nil {
-		task.Status = data.GetTaskStatus(input.Status)
-	}
-
 	v := validator.New()
 
-	if data.ValidateTask(v, task); !v.Valid() {
+	data.ValidateTask(v, task, workflow)
+	if err := t.replaceCustomFields(v, task, doc.CustomFields); err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
 		t.error.FaildErrorResponse(w, r, v.Errors)
 		return
 	}
 
-	err = t.models.Tasks.Update(task)
+	err = t.models.Tasks.Update(task, user.ID)
 	if err != nil {
+		if key, msg, ok := taskRelationError(err); ok {
+			v.AddError(key, msg)
+			t.error.FaildErrorResponse(w, r, v.Errors)
+			return
+		}
+		if t.blockedResponse(w, r, err) {
+			return
+		}
+		if errors.Is(err, data.ErrEditConflict) {
+			t.error.PreconditionFailedResponse(w, r)
+			return
+		}
 		t.error.ServerErrorResponse(w, r, err)
 		return
 	}
 
-	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "task updated successfully"})
+	env := response.Envelope{"message": "task updated successfully", "version": task.Version}
+	if task.NextOccurrenceID != nil {
+		env["next_occurrence_id"] = *task.NextOccurrenceID
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", taskETag(task))
+
+	err = response.JSONWithHeaders(w, http.StatusOK, env, headers)
 	if err != nil {
 		t.error.ServerErrorResponse(w, r, err)
 		return
 	}
 }
+
+// readTaskFilters reads the filtering, sorting and pagination parameters of the
+// task listing endpoints.
+func readTaskFilters(qs url.Values, v *validator.Validator) data.TaskFilters {
+	var filters data.TaskFilters
+
+	for _, status := range readCSV(qs, "status", nil) {
+		filters.Statuses = append(filters.Statuses, data.GetTaskStatus(&status))
+	}
+	for _, priority := range readCSV(qs, "priority", nil) {
+		filters.Priorities = append(filters.Priorities, data.GetTaskPriority(&priority))
+	}
+	filters.Search = strings.TrimSpace(readString(qs, "q", ""))
+	filters.DueBefore = readTime(qs, "due_before", v)
+	filters.DueAfter = readTime(qs, "due_after", v)
+	filters.Overdue = readBool(qs, "overdue", v)
+	filters.Due = strings.ToLower(readString(qs, "due", ""))
+	filters.Labels = readCSV(qs, "label", nil)
+	filters.LabelMatch = strings.ToLower(readString(qs, "label_match", data.LabelMatchAny))
+
+	if assignee := qs.Get("assignee"); assignee != "" {
+		id, err := strconv.Atoi(assignee)
+		if err != nil || id < 1 {
+			v.AddError("assignee", "must be a user id")
+		} else {
+			filters.AssigneeID = &id
+		}
+	}
+
+	// archived tasks are left out unless asked for, ?archived=all lists both
+	switch qs.Get("archived") {
+	case "all":
+	case "":
+		filters.Archived = new(bool)
+	default:
+		filters.Archived = readBool(qs, "archived", v)
+	}
+
+	switch project := qs.Get("project"); project {
+	case "":
+	case "inbox":
+		filters.Inbox = true
+	default:
+		id, err := strconv.Atoi(project)
+		if err != nil || id < 1 {
+			v.AddError("project", "must be a project id or `inbox`")
+			break
+		}
+		filters.ProjectID = &id
+	}
+
+	for param := range qs {
+		if filter, ok := data.ParseFieldFilter(param, qs.Get(param)); ok {
+			filters.Fields = append(filters.Fields, filter)
+		}
+	}
+	// map order is random, keep the query stable
+	slices.SortFunc(filters.Fields, func(a, b data.FieldFilter) int {
+		return strings.Compare(a.Key+"."+a.Op, b.Key+"."+b.Op)
+	})
+
+	filters.Page = readInt(qs, "page", 1, v)
+	filters.PageSize = readInt(qs, "page_size", 20, v)
+
+	// an empty ?cursor= asks for the first page in cursor mode
+	if qs.Has("cursor") {
+		filters.UseCursor = true
+		filters.Sort = readCSV(qs, "sort", nil)
+
+		if cursor := qs.Get("cursor"); cursor != "" {
+			decoded, err := data.DecodeCursor(cursor)
+			if err != nil {
+				v.AddError("cursor", "must be a cursor returned by a previous request")
+			}
+			filters.Cursor = decoded
+		}
+
+		return filters
+	}
+
+	filters.Sort = readCSV(qs, "sort", []string{"id"})
+
+	return filters
+}
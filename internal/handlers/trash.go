@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// HandleGetTrash lists the trashed tasks the user can see. Subtasks trashed
+// along with their parent are left out, they come back with it.
+func (t tasksHandler) HandleGetTrash(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters := data.Filters{
+		Page:     readInt(qs, "page", 1, v),
+		PageSize: readInt(qs, "page_size", 20, v),
+	}
+
+	if data.ValidateFilters(v, filters, nil); !v.Valid() {
+		t.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	tasks, metadata, err := t.models.Tasks.GetTrash(user.ID, filters)
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"tasks": tasks, "metadata": metadata})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (t tasksHandler) HandleRestoreTask(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	task, err := t.models.Tasks.Restore(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found in the trash")
+		case errors.Is(err, data.ErrParentTrashed):
+			t.error.ConflictResponse(w, r, "the task's parent is in the trash, restore it first")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"task": task})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleDeleteTaskPermanently removes a trashed task, its subtasks and their
+// attachments for good.
+func (t tasksHandler) HandleDeleteTaskPermanently(w http.ResponseWriter, r *http.Request) {
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		t.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	keys, err := t.models.Tasks.DeletePermanently(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			t.error.NotFoundResponse(w, r, "task not found in the trash")
+		default:
+			t.error.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	removeBlobs(t.storage, t.background, t.error.Logger, keys)
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"message": "task deleted permanently"})
+	if err != nil {
+		t.error.ServerErrorResponse(w, r, err)
+	}
+}
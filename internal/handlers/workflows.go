@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/moutafatin/go-tasks-management-api/internal/ctx"
+	"github.com/moutafatin/go-tasks-management-api/internal/data"
+	"github.com/moutafatin/go-tasks-management-api/internal/request"
+	"github.com/moutafatin/go-tasks-management-api/internal/response"
+	"github.com/moutafatin/go-tasks-management-api/internal/validator"
+)
+
+// workflowsHandler serves the workflow of a project under
+// /projects/{id}/workflow, and the one of the current user's tasks outside
+// of any project under /me/workflow.
+type workflowsHandler struct {
+	models data.Models
+	error  response.ErrorResponse
+}
+
+// readWorkflowProject returns the project of the route, nil for /me.
+func readWorkflowProject(r *http.Request) (*int, error) {
+	if chi.URLParam(r, "id") == "" {
+		return nil, nil
+	}
+
+	id, err := readIntParam(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func (wh workflowsHandler) HandleGetWorkflow(w http.ResponseWriter, r *http.Request) {
+	projectID, err := readWorkflowProject(r)
+	if err != nil {
+		wh.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	workflow, err := wh.models.Workflows.Get(projectID, user.ID)
+	if err != nil {
+		wh.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"workflow": workflow})
+	if err != nil {
+		wh.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (wh workflowsHandler) HandleReplaceWorkflow(w http.ResponseWriter, r *http.Request) {
+	projectID, err := readWorkflowProject(r)
+	if err != nil {
+		wh.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	var input struct {
+		States []struct {
+			Name       string `json:"name"`
+			IsTerminal bool   `json:"is_terminal"`
+		} `json:"states"`
+		Transitions []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"transitions"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		wh.error.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	workflow := &data.Workflow{
+		ProjectID:   projectID,
+		UserID:      user.ID,
+		States:      []data.WorkflowState{},
+		Transitions: []data.WorkflowTransition{},
+	}
+	for _, state := range input.States {
+		workflow.States = append(workflow.States, data.WorkflowState{Name: data.GetTaskStatus(&state.Name), IsTerminal: state.IsTerminal})
+	}
+	for _, transition := range input.Transitions {
+		workflow.Transitions = append(workflow.Transitions, data.WorkflowTransition{From: data.GetTaskStatus(&transition.From), To: data.GetTaskStatus(&transition.To)})
+	}
+
+	v := validator.New()
+
+	if data.ValidateWorkflow(v, workflow); !v.Valid() {
+		wh.error.FaildErrorResponse(w, r, v.Errors)
+		return
+	}
+
+	err = wh.models.Workflows.Replace(workflow)
+	if err != nil {
+		if wh.inUseResponse(w, r, err) {
+			return
+		}
+		wh.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"workflow": workflow})
+	if err != nil {
+		wh.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// HandleResetWorkflow goes back to the default workflow.
+func (wh workflowsHandler) HandleResetWorkflow(w http.ResponseWriter, r *http.Request) {
+	projectID, err := readWorkflowProject(r)
+	if err != nil {
+		wh.error.BadRequestResponse(w, r, ErrInvalidIdParam)
+		return
+	}
+
+	user := ctx.ContextGetUser(r)
+
+	workflow, err := wh.models.Workflows.Reset(projectID, user.ID)
+	if err != nil {
+		if wh.inUseResponse(w, r, err) {
+			return
+		}
+		wh.error.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, response.Envelope{"workflow": workflow})
+	if err != nil {
+		wh.error.ServerErrorResponse(w, r, err)
+	}
+}
+
+// inUseResponse writes a 409 response and returns true when err is a
+// *data.WorkflowInUseError.
+func (wh workflowsHandler) inUseResponse(w http.ResponseWriter, r *http.Request, err error) bool {
+	var inUse *data.WorkflowInUseError
+	if !errors.As(err, &inUse) {
+		return false
+	}
+
+	wh.error.ConflictResponse(w, r, map[string]any{
+		"message":  "the workflow must keep the states tasks are in, move the tasks out of them first",
+		"statuses": inUse.Statuses,
+	})
+
+	return true
+}
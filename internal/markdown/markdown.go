@@ -0,0 +1,189 @@
+// Package markdown renders the small Markdown subset used in comments to
+// HTML. The source is HTML-escaped before any markup is produced, so the
+// output only ever contains the tags generated here and links with safe
+// schemes.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingRX     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	unorderedRX   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedRX     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	blockquoteRX  = regexp.MustCompile(`^&gt;\s?(.*)$`)
+	codeSpanRX    = regexp.MustCompile("`([^`]+)`")
+	linkRX        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongRX      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emphasisRX    = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+	strikeRX      = regexp.MustCompile(`~~([^~]+)~~`)
+	placeholderRX = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+var allowedSchemes = []string{"http", "https", "mailto"}
+
+// Render converts Markdown to sanitized HTML. It supports paragraphs,
+// headings, ordered and unordered lists, block quotes, fenced code blocks,
+// code spans, strong, emphasis, strikethrough and links.
+func Render(src string) string {
+	// NUL bytes are used as code span placeholders
+	src = strings.ReplaceAll(src, "\x00", "")
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	var out strings.Builder
+	var paragraph []string
+	var list []string
+	listTag := ""
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + renderInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	flushList := func() {
+		if len(list) > 0 {
+			out.WriteString("<" + listTag + ">\n")
+			for _, item := range list {
+				out.WriteString("<li>" + renderInline(item) + "</li>\n")
+			}
+			out.WriteString("</" + listTag + ">\n")
+			list, listTag = nil, ""
+		}
+	}
+	flush := func() {
+		flushParagraph()
+		flushList()
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := html.EscapeString(lines[i])
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, html.EscapeString(lines[i]))
+			}
+
+			out.WriteString("<pre><code>" + strings.Join(code, "\n") + "</code></pre>\n")
+			continue
+		}
+
+		if trimmed == "" {
+			flush()
+			continue
+		}
+
+		if m := headingRX.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			level := len(m[1])
+			out.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", level, renderInline(m[2]), level))
+			continue
+		}
+
+		if m := blockquoteRX.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			out.WriteString("<blockquote><p>" + renderInline(m[1]) + "</p></blockquote>\n")
+			continue
+		}
+
+		tag, item := "", ""
+		if m := unorderedRX.FindStringSubmatch(line); m != nil {
+			tag, item = "ul", m[1]
+		} else if m := orderedRX.FindStringSubmatch(line); m != nil {
+			tag, item = "ol", m[1]
+		}
+
+		if tag != "" {
+			flushParagraph()
+			if listTag != tag {
+				flushList()
+				listTag = tag
+			}
+			list = append(list, item)
+			continue
+		}
+
+		flushList()
+		paragraph = append(paragraph, trimmed)
+	}
+
+	flush()
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// renderInline applies the inline markup to already escaped text.
+func renderInline(s string) string {
+	// code spans and links are swapped for placeholders, so the other rules
+	// neither apply inside code nor inject tags into an href
+	var spans []string
+	hold := func(html string) string {
+		spans = append(spans, html)
+		return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+	}
+
+	s = codeSpanRX.ReplaceAllStringFunc(s, func(m string) string {
+		return hold("<code>" + codeSpanRX.FindStringSubmatch(m)[1] + "</code>")
+	})
+
+	s = linkRX.ReplaceAllStringFunc(s, func(m string) string {
+		parts := linkRX.FindStringSubmatch(m)
+		text := restore(renderEmphasis(parts[1]), spans)
+
+		href, ok := safeURL(parts[2])
+		if !ok {
+			return hold(text)
+		}
+		return hold(`<a href="` + href + `" rel="nofollow noopener">` + text + `</a>`)
+	})
+
+	s = renderEmphasis(s)
+	s = strings.ReplaceAll(s, "\n", "<br>\n")
+
+	return restore(s, spans)
+}
+
+// renderEmphasis applies strong, emphasis and strikethrough.
+func renderEmphasis(s string) string {
+	s = strongRX.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = emphasisRX.ReplaceAllString(s, "<em>$1$2</em>")
+	return strikeRX.ReplaceAllString(s, "<del>$1</del>")
+}
+
+// restore puts the held spans back in place of their placeholders.
+func restore(s string, spans []string) string {
+	return placeholderRX.ReplaceAllStringFunc(s, func(m string) string {
+		var i int
+		fmt.Sscanf(placeholderRX.FindStringSubmatch(m)[1], "%d", &i)
+		return spans[i]
+	})
+}
+
+// safeURL validates an escaped link target and returns it escaped for use in
+// an attribute.
+func safeURL(escaped string) (string, bool) {
+	raw := html.UnescapeString(escaped)
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	for _, allowed := range allowedSchemes {
+		if scheme == allowed {
+			return html.EscapeString(u.String()), true
+		}
+	}
+
+	return "", false
+}
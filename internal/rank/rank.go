@@ -0,0 +1,137 @@
+// Package rank generates lexicographic sort keys for manually ordered lists.
+// A key can always be generated between two others, so moving an item only
+// rewrites that item's key.
+//
+// Keys use base 62 digits in ASCII order and never end in the lowest digit,
+// which keeps room below every key. They must be compared bytewise, e.g.
+// with COLLATE "C" in Postgres.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// MaxLength is the key length past which a list should be respread, see
+// Spread. Inserting over and over at the same spot makes keys grow.
+const MaxLength = 64
+
+var ErrInvalidRange = errors.New("rank: keys are not in ascending order")
+
+// Between returns a key that sorts after a and before b. An empty a means the
+// start of the list and an empty b its end.
+func Between(a, b string) (string, error) {
+	if !valid(a) || !valid(b) {
+		return "", errors.New("rank: invalid key")
+	}
+	if b != "" && a >= b {
+		return "", ErrInvalidRange
+	}
+
+	if b == "" {
+		return after(a), nil
+	}
+
+	return midpoint(a, b), nil
+}
+
+// after returns a short key past a. Appending is the most common move, so it
+// steps one digit at a time instead of halving the remaining range.
+func after(a string) string {
+	if a == "" {
+		return midpoint("", "")
+	}
+
+	if d := strings.IndexByte(digits, a[0]); d < len(digits)-1 {
+		return string(digits[d+1])
+	}
+
+	return a[:1] + after(a[1:])
+}
+
+// Spread returns n short, evenly spaced keys in ascending order, to renumber
+// a list whose keys grew too long.
+func Spread(n int) []string {
+	width, space := 1, uint64(len(digits))
+	for space < uint64(n+1)*uint64(len(digits)) {
+		width++
+		space *= uint64(len(digits))
+	}
+
+	step := space / uint64(n+1)
+	keys := make([]string, n)
+
+	for i := range keys {
+		value := step * uint64(i+1)
+		key := make([]byte, width)
+		for j := width - 1; j >= 0; j-- {
+			key[j] = digits[value%uint64(len(digits))]
+			value /= uint64(len(digits))
+		}
+		keys[i] = strings.TrimRight(string(key), digits[:1])
+	}
+
+	return keys
+}
+
+func valid(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+
+	return key == "" || key[len(key)-1] != digits[0]
+}
+
+// midpoint assumes a < b, with b == "" standing for the end of the list.
+func midpoint(a, b string) string {
+	if b != "" {
+		// skip the common prefix, reading a as padded with the lowest digit
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(digits, a[0])
+	}
+
+	digitB := len(digits)
+	if b != "" {
+		digitB = strings.IndexByte(digits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(digits[(digitA+digitB+1)/2])
+	}
+
+	// the first digits are consecutive
+	if len(b) > 1 {
+		return b[:1]
+	}
+
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[digitA]) + midpoint(rest, "")
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return digits[0]
+}
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +18,15 @@ func DecodeJSONStrict(w http.ResponseWriter, r *http.Request, dst interface{}) e
 	return decodeJSON(w, r, dst, true)
 }
 
+// DecodeJSONBytes decodes a JSON document the way DecodeJSONStrict decodes
+// a request body.
+func DecodeJSONBytes(data []byte, dst interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	return decode(dec, dst, len(data))
+}
+
 func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, disallowUnknownFields bool) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -27,6 +37,10 @@ func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, disallo
 		dec.DisallowUnknownFields()
 	}
 
+	return decode(dec, dst, maxBytes)
+}
+
+func decode(dec *json.Decoder, dst interface{}, maxBytes int) error {
 	err := dec.Decode(dst)
 	if err != nil {
 		var syntaxError *json.SyntaxError
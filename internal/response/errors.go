@@ -73,3 +73,30 @@ func (e ErrorResponse) InactiveAccountResponse(w http.ResponseWriter, r *http.Re
 	message := "your user account must be activated to access this resource"
 	e.ErrorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (e ErrorResponse) NotPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your role does not allow you to perform this action"
+	e.ErrorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (e ErrorResponse) ContentTooLargeResponse(w http.ResponseWriter, r *http.Request, message string) {
+	e.ErrorResponse(w, r, http.StatusRequestEntityTooLarge, message)
+}
+
+func (e ErrorResponse) ConflictResponse(w http.ResponseWriter, r *http.Request, message any) {
+	e.ErrorResponse(w, r, http.StatusConflict, message)
+}
+
+func (e ErrorResponse) UnsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, message string) {
+	e.ErrorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
+func (e ErrorResponse) PreconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the record was changed since you fetched it, fetch it again and retry"
+	e.ErrorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+func (e ErrorResponse) PreconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the request must be conditional, send the record's ETag in an If-Match header"
+	e.ErrorResponse(w, r, http.StatusPreconditionRequired, message)
+}
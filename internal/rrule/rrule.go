@@ -0,0 +1,375 @@
+// Package rrule parses and expands the subset of RFC 5545 recurrence rules
+// used by recurring tasks: FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds the expansion of rules whose BY parts rarely or never
+// match, such as FREQ=MONTHLY;BYMONTHDAY=31;BYDAY=MO.
+const maxPeriods = 100_000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Weekday is a BYDAY entry. N is the ordinal within the month, e.g. 1 for
+// 1MO or -1 for -1FR, and 0 for every such weekday.
+type Weekday struct {
+	Day time.Weekday
+	N   int
+}
+
+func (wd Weekday) String() string {
+	for code, day := range weekdays {
+		if day == wd.Day {
+			if wd.N != 0 {
+				return strconv.Itoa(wd.N) + code
+			}
+			return code
+		}
+	}
+	return ""
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+// Parse parses a recurrence rule such as "FREQ=WEEKLY;BYDAY=MO,WE". An
+// "RRULE:" prefix is accepted and part names are case insensitive.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return nil, errors.New("must not be empty")
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(s, ";") {
+		// a trailing ";" is common enough to let through
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("malformed part %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s must only be given once", name)
+		}
+		seen[name] = true
+
+		var err error
+
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+			if !slices.Contains([]Frequency{Daily, Weekly, Monthly, Yearly}, rule.Freq) {
+				return nil, errors.New("FREQ must be one of DAILY, WEEKLY, MONTHLY, YEARLY")
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err != nil || rule.Interval < 1 || rule.Interval > 1000 {
+				return nil, errors.New("INTERVAL must be a number between 1 and 1000")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err != nil || rule.Count < 1 {
+				return nil, errors.New("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				wd, err := parseWeekday(v)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(v)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY value %q must be between 1 and 31 or -31 and -1", v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported part %s", name)
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return nil, errors.New("FREQ must be provided")
+	case rule.Count > 0 && rule.Until != nil:
+		return nil, errors.New("COUNT and UNTIL must not be combined")
+	case rule.Freq == Yearly && (len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0):
+		return nil, errors.New("BYDAY and BYMONTHDAY are not supported with FREQ=YEARLY")
+	case rule.Freq == Weekly && len(rule.ByMonthDay) > 0:
+		// RFC 5545 doesn't allow it
+		return nil, errors.New("BYMONTHDAY must not be used with FREQ=WEEKLY")
+	}
+
+	if rule.Freq != Monthly {
+		for _, wd := range rule.ByDay {
+			if wd.N != 0 {
+				return nil, errors.New("BYDAY ordinals such as 1MO are only supported with FREQ=MONTHLY")
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+func parseWeekday(s string) (Weekday, error) {
+	if len(s) < 2 {
+		return Weekday{}, fmt.Errorf("BYDAY value %q is not a weekday", s)
+	}
+
+	day, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return Weekday{}, fmt.Errorf("BYDAY value %q is not a weekday", s)
+	}
+
+	wd := Weekday{Day: day}
+
+	if ordinal := s[:len(s)-2]; ordinal != "" {
+		n, err := strconv.Atoi(ordinal)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return Weekday{}, fmt.Errorf("BYDAY ordinal in %q must be between 1 and 5 or -5 and -1", s)
+		}
+		wd.N = n
+	}
+
+	return wd, nil
+}
+
+// parseUntil accepts the UTC, floating and date forms of UNTIL. Floating
+// times are read as UTC and a date includes the whole day.
+func parseUntil(s string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102T150405", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+
+	return time.Time{}, errors.New("UNTIL must look like 20060102T150405Z or 20060102")
+}
+
+// String returns the rule in canonical form.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Next returns up to n occurrences of the series starting at dtstart that
+// fall strictly after t. COUNT is counted from dtstart, so the result is the
+// same wherever in the series t falls.
+func (r *Rule) Next(dtstart, t time.Time, n int) []time.Time {
+	var occurrences []time.Time
+	if n < 1 {
+		return occurrences
+	}
+
+	count := 0
+
+	for period := 0; period < maxPeriods; period++ {
+		for _, occurrence := range r.candidates(dtstart, period) {
+			if occurrence.Before(dtstart) {
+				continue
+			}
+			if r.Until != nil && occurrence.After(*r.Until) {
+				return occurrences
+			}
+
+			count++
+			if occurrence.After(t) {
+				occurrences = append(occurrences, occurrence)
+				if len(occurrences) == n {
+					return occurrences
+				}
+			}
+			if r.Count > 0 && count >= r.Count {
+				return occurrences
+			}
+		}
+	}
+
+	return occurrences
+}
+
+// After returns the first occurrence of the series starting at dtstart that
+// falls strictly after t. It reports false once the series has ended.
+func (r *Rule) After(dtstart, t time.Time) (time.Time, bool) {
+	next := r.Next(dtstart, t, 1)
+	if len(next) == 0 {
+		return time.Time{}, false
+	}
+
+	return next[0], true
+}
+
+// candidates returns, in order, the occurrences within the period-th
+// repetition of the rule's frequency, keeping dtstart's time of day.
+func (r *Rule) candidates(dtstart time.Time, period int) []time.Time {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+	}
+
+	var days []time.Time
+
+	switch r.Freq {
+	case Daily:
+		day := dtstart.AddDate(0, 0, period*r.Interval)
+		days = append(days, day)
+
+	case Weekly:
+		monday := dtstart.AddDate(0, 0, -((int(dtstart.Weekday())+6)%7)+period*r.Interval*7)
+		if len(r.ByDay) == 0 {
+			days = append(days, monday.AddDate(0, 0, (int(dtstart.Weekday())+6)%7))
+			break
+		}
+		for offset := 0; offset < 7; offset++ {
+			days = append(days, monday.AddDate(0, 0, offset))
+		}
+
+	case Monthly:
+		first := at(dtstart.Year(), dtstart.Month()+time.Month(period*r.Interval), 1)
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			// months without dtstart's day, such as the 31st, are skipped
+			if day := at(first.Year(), first.Month(), dtstart.Day()); day.Month() == first.Month() {
+				days = append(days, day)
+			}
+			break
+		}
+		for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+			days = append(days, day)
+		}
+
+	case Yearly:
+		day := at(dtstart.Year()+period*r.Interval, dtstart.Month(), dtstart.Day())
+		// February 29th only occurs in leap years
+		if day.Day() == dtstart.Day() {
+			days = append(days, day)
+		}
+	}
+
+	matching := days[:0]
+	for _, day := range days {
+		if r.matches(day) {
+			matching = append(matching, day)
+		}
+	}
+
+	return matching
+}
+
+// matches reports whether day satisfies the BYDAY and BYMONTHDAY parts.
+func (r *Rule) matches(day time.Time) bool {
+	lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	if len(r.ByMonthDay) > 0 {
+		ok := false
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = lastDay + d + 1
+			}
+			if d == day.Day() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if len(r.ByDay) > 0 {
+		ok := false
+		for _, wd := range r.ByDay {
+			if wd.Day != day.Weekday() {
+				continue
+			}
+			switch {
+			case wd.N > 0:
+				ok = (day.Day()-1)/7+1 == wd.N
+			case wd.N < 0:
+				ok = (lastDay-day.Day())/7+1 == -wd.N
+			default:
+				ok = true
+			}
+			if ok {
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
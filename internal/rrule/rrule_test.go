@@ -0,0 +1,165 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{name: "daily", rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{name: "prefix and lower case", rule: "rrule:freq=weekly;byday=mo,we", want: "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{name: "trailing semicolon", rule: "FREQ=DAILY;", want: "FREQ=DAILY"},
+		{name: "interval of one is implied", rule: "FREQ=DAILY;INTERVAL=1", want: "FREQ=DAILY"},
+		{name: "monthly ordinal", rule: "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR", want: "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR"},
+		{name: "monthly by day", rule: "FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=3", want: "FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=3"},
+		{name: "until date", rule: "FREQ=DAILY;UNTIL=20260131", want: "FREQ=DAILY;UNTIL=20260131T235959Z"},
+		{name: "empty", rule: " ", wantErr: true},
+		{name: "no frequency", rule: "INTERVAL=2", wantErr: true},
+		{name: "unknown frequency", rule: "FREQ=HOURLY", wantErr: true},
+		{name: "malformed part", rule: "FREQ=DAILY;COUNT", wantErr: true},
+		{name: "repeated part", rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{name: "unsupported part", rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+		{name: "interval out of range", rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "count and until", rule: "FREQ=DAILY;COUNT=2;UNTIL=20260131", wantErr: true},
+		{name: "bad weekday", rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "bad month day", rule: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{name: "weekly by month day", rule: "FREQ=WEEKLY;BYMONTHDAY=31", wantErr: true},
+		{name: "yearly by day", rule: "FREQ=YEARLY;BYDAY=MO", wantErr: true},
+		{name: "weekly ordinal", rule: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %q, want an error", tt.rule, rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse(time.DateTime, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		after   string
+		n       int
+		want    []string
+	}{
+		{
+			name:    "daily",
+			rule:    "FREQ=DAILY;INTERVAL=2",
+			dtstart: "2026-01-01 09:00:00",
+			after:   "2026-01-01 09:00:00",
+			n:       3,
+			want:    []string{"2026-01-03 09:00:00", "2026-01-05 09:00:00", "2026-01-07 09:00:00"},
+		},
+		{
+			name:    "weekly on weekdays",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,FR",
+			dtstart: "2026-01-07 09:00:00", // a Wednesday
+			after:   "2026-01-01 00:00:00",
+			n:       3,
+			want:    []string{"2026-01-09 09:00:00", "2026-01-12 09:00:00", "2026-01-16 09:00:00"},
+		},
+		{
+			name:    "monthly skips short months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: "2026-01-31 09:00:00",
+			after:   "2026-01-31 09:00:00",
+			n:       2,
+			want:    []string{"2026-03-31 09:00:00", "2026-05-31 09:00:00"},
+		},
+		{
+			name:    "monthly last friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: "2026-01-01 09:00:00",
+			after:   "2026-01-01 09:00:00",
+			n:       2,
+			want:    []string{"2026-01-30 09:00:00", "2026-02-27 09:00:00"},
+		},
+		{
+			name:    "monthly last day",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: "2026-01-15 09:00:00",
+			after:   "2026-01-15 09:00:00",
+			n:       2,
+			want:    []string{"2026-01-31 09:00:00", "2026-02-28 09:00:00"},
+		},
+		{
+			name:    "yearly leap day",
+			rule:    "FREQ=YEARLY",
+			dtstart: "2024-02-29 09:00:00",
+			after:   "2024-02-29 09:00:00",
+			n:       1,
+			want:    []string{"2028-02-29 09:00:00"},
+		},
+		{
+			name:    "count is counted from dtstart",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: "2026-01-01 09:00:00",
+			after:   "2026-01-01 12:00:00",
+			n:       5,
+			want:    []string{"2026-01-02 09:00:00", "2026-01-03 09:00:00"},
+		},
+		{
+			name:    "until",
+			rule:    "FREQ=DAILY;UNTIL=20260102",
+			dtstart: "2026-01-01 09:00:00",
+			after:   "2026-01-01 09:00:00",
+			n:       5,
+			want:    []string{"2026-01-02 09:00:00"},
+		},
+		{
+			name:    "never matching",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31;BYDAY=1MO",
+			dtstart: "2026-01-01 09:00:00",
+			after:   "2026-01-01 09:00:00",
+			n:       1,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+
+			got := rule.Next(date(tt.dtstart), date(tt.after), tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Next = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(date(tt.want[i])) {
+					t.Errorf("Next[%d] = %s, want %s", i, got[i].Format(time.DateTime), tt.want[i])
+				}
+			}
+		})
+	}
+}
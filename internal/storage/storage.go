@@ -0,0 +1,25 @@
+// Package storage keeps binary blobs, such as task attachments, outside of
+// Postgres. Blobs are addressed by opaque keys chosen by the caller.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store is implemented by every blob backend. The local filesystem backend is
+// the only one for now; an S3-compatible backend only has to satisfy the same
+// interface.
+type Store interface {
+	// Put streams r into the blob stored under key, replacing any existing
+	// one, and returns the number of bytes written.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Get opens the blob stored under key. Callers must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
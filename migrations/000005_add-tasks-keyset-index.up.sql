@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS tasks_user_id_created_at_id_idx ON tasks (user_id, created_at, id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS tasks_user_id_due_at_idx;
+
+ALTER TABLE tasks
+DROP CONSTRAINT tasks_start_before_due_check;
+
+ALTER TABLE tasks
+DROP COLUMN start_at,
+DROP COLUMN due_at;
@@ -0,0 +1,8 @@
+ALTER TABLE tasks
+ADD COLUMN start_at timestamp(0) with time zone,
+ADD COLUMN due_at timestamp(0) with time zone;
+
+ALTER TABLE tasks
+ADD CONSTRAINT tasks_start_before_due_check CHECK (start_at < due_at);
+
+CREATE INDEX IF NOT EXISTS tasks_user_id_due_at_idx ON tasks (user_id, due_at);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS tasks_parent_id_idx;
+
+ALTER TABLE tasks
+DROP CONSTRAINT tasks_parent_not_self_check;
+
+ALTER TABLE tasks
+DROP CONSTRAINT fk_parent_id;
+
+ALTER TABLE tasks
+DROP COLUMN parent_id;
@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+ADD COLUMN parent_id INTEGER CONSTRAINT fk_parent_id REFERENCES tasks (id) ON DELETE CASCADE,
+ADD CONSTRAINT tasks_parent_not_self_check CHECK (parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS tasks_parent_id_idx ON tasks (parent_id);
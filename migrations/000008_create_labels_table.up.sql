@@ -0,0 +1,18 @@
+CREATE TABLE
+  IF NOT EXISTS labels (
+    id serial PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name citext NOT NULL,
+    color text NOT NULL DEFAULT '#808080',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT labels_user_id_name_key UNIQUE (user_id, name)
+  );
+
+CREATE TABLE
+  IF NOT EXISTS task_labels (
+    task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES labels (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, label_id)
+  );
+
+CREATE INDEX IF NOT EXISTS task_labels_label_id_idx ON task_labels (label_id);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS tasks_project_id_idx;
+
+ALTER TABLE tasks
+DROP CONSTRAINT fk_project_id;
+
+ALTER TABLE tasks
+DROP COLUMN project_id;
+
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,17 @@
+CREATE TABLE
+  IF NOT EXISTS projects (
+    id serial PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    color text NOT NULL DEFAULT '#808080',
+    archived bool NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+CREATE INDEX IF NOT EXISTS projects_user_id_idx ON projects (user_id);
+
+ALTER TABLE tasks
+ADD COLUMN project_id INTEGER CONSTRAINT fk_project_id REFERENCES projects (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_project_id_idx ON tasks (project_id);
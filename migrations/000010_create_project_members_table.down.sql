@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS project_invitations;
+
+DROP TABLE IF EXISTS project_members;
+
+DROP TYPE IF EXISTS project_role;
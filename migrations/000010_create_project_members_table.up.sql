@@ -0,0 +1,30 @@
+CREATE TYPE project_role AS ENUM ('owner', 'editor', 'viewer');
+
+CREATE TABLE
+  IF NOT EXISTS project_members (
+    project_id INTEGER NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role project_role NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, user_id)
+  );
+
+CREATE INDEX IF NOT EXISTS project_members_user_id_idx ON project_members (user_id);
+
+-- every existing project is owned by the user who created it
+INSERT INTO project_members (project_id, user_id, role)
+SELECT id, user_id, 'owner' FROM projects;
+
+CREATE TABLE
+  IF NOT EXISTS project_invitations (
+    id serial PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    email citext NOT NULL,
+    role project_role NOT NULL,
+    hash bytea UNIQUE NOT NULL,
+    invited_by INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expiry timestamp(0) with time zone NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+CREATE INDEX IF NOT EXISTS project_invitations_project_id_idx ON project_invitations (project_id);
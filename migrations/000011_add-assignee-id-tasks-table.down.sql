@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS tasks_assignee_id_idx;
+
+ALTER TABLE tasks
+DROP CONSTRAINT fk_assignee_id;
+
+ALTER TABLE tasks
+DROP COLUMN assignee_id;
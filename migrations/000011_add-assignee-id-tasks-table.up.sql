@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+ADD COLUMN assignee_id INTEGER CONSTRAINT fk_assignee_id REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_assignee_id_idx ON tasks (assignee_id);
@@ -0,0 +1,16 @@
+CREATE TABLE
+  IF NOT EXISTS task_attachments (
+    id serial PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL CHECK (size >= 0),
+    sha256 char(64) NOT NULL,
+    storage_key text NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+CREATE INDEX IF NOT EXISTS task_attachments_task_id_idx ON task_attachments (task_id);
+
+CREATE INDEX IF NOT EXISTS task_attachments_user_id_idx ON task_attachments (user_id);
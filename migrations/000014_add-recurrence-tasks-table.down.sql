@@ -0,0 +1,7 @@
+ALTER TABLE tasks
+DROP CONSTRAINT IF EXISTS tasks_recurrence_due_check;
+
+ALTER TABLE tasks
+DROP COLUMN IF EXISTS recurrence_prev_id,
+DROP COLUMN IF EXISTS recurrence_start,
+DROP COLUMN IF EXISTS recurrence;
@@ -0,0 +1,7 @@
+ALTER TABLE tasks
+ADD COLUMN recurrence text,
+ADD COLUMN recurrence_start timestamp(0) with time zone,
+ADD COLUMN recurrence_prev_id INTEGER UNIQUE REFERENCES tasks (id) ON DELETE SET NULL;
+
+ALTER TABLE tasks
+ADD CONSTRAINT tasks_recurrence_due_check CHECK (recurrence IS NULL OR (due_at IS NOT NULL AND recurrence_start IS NOT NULL));
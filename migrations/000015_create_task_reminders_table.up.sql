@@ -0,0 +1,16 @@
+CREATE TABLE
+  IF NOT EXISTS task_reminders (
+    id serial PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    remind_at timestamp(0) with time zone,
+    offset_minutes INTEGER CHECK (offset_minutes >= 0),
+    sent_at timestamp(0) with time zone,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT task_reminders_time_check CHECK ((remind_at IS NULL) <> (offset_minutes IS NULL))
+  );
+
+CREATE INDEX IF NOT EXISTS task_reminders_task_id_idx ON task_reminders (task_id);
+
+CREATE INDEX IF NOT EXISTS task_reminders_pending_idx ON task_reminders (remind_at) WHERE sent_at IS NULL;
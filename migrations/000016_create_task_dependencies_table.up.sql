@@ -0,0 +1,10 @@
+CREATE TABLE
+  IF NOT EXISTS task_dependencies (
+    task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    blocked_by_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, blocked_by_id),
+    CONSTRAINT task_dependencies_self_check CHECK (task_id <> blocked_by_id)
+  );
+
+CREATE INDEX IF NOT EXISTS task_dependencies_blocked_by_id_idx ON task_dependencies (blocked_by_id);
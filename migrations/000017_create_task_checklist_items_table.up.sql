@@ -0,0 +1,12 @@
+CREATE TABLE
+  IF NOT EXISTS task_checklist_items (
+    id serial PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    text text NOT NULL,
+    checked boolean NOT NULL DEFAULT false,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+CREATE INDEX IF NOT EXISTS task_checklist_items_task_id_position_idx ON task_checklist_items (task_id, position);
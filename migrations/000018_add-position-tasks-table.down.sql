@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS tasks_user_id_status_position_idx;
+
+DROP INDEX IF EXISTS tasks_project_id_status_position_idx;
+
+ALTER TABLE tasks
+DROP COLUMN IF EXISTS position;
@@ -0,0 +1,19 @@
+ALTER TABLE tasks
+ADD COLUMN position text COLLATE "C";
+
+-- number every board column in id order, with keys the rank package can
+-- insert between
+UPDATE tasks t
+SET position = lpad(r.n::text, 10, '0') || 'V'
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY COALESCE(project_id, -user_id), status ORDER BY id) AS n
+    FROM tasks
+  ) r
+WHERE t.id = r.id;
+
+ALTER TABLE tasks
+ALTER COLUMN position SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_project_id_status_position_idx ON tasks (project_id, status, position);
+
+CREATE INDEX IF NOT EXISTS tasks_user_id_status_position_idx ON tasks (user_id, status, position) WHERE project_id IS NULL;
@@ -0,0 +1,47 @@
+CREATE TYPE task_status AS ENUM ('TODO', 'IN_PROGRESS', 'DONE');
+
+-- custom states fall back to the closest default one
+UPDATE tasks t
+SET
+  status = CASE
+    WHEN s.is_terminal THEN 'DONE'
+    WHEN s.position = 0 THEN 'TODO'
+    ELSE 'IN_PROGRESS'
+  END
+FROM
+  workflows w
+  INNER JOIN workflow_states s ON s.workflow_id = w.id
+WHERE
+  s.name = t.status
+  AND (
+    w.project_id = t.project_id
+    OR (
+      t.project_id IS NULL
+      AND w.project_id IS NULL
+      AND w.user_id = t.user_id
+    )
+  )
+  AND t.status NOT IN ('TODO', 'IN_PROGRESS', 'DONE');
+
+UPDATE tasks
+SET
+  status = 'TODO'
+WHERE
+  status NOT IN ('TODO', 'IN_PROGRESS', 'DONE');
+
+ALTER TABLE tasks
+ALTER COLUMN status
+DROP DEFAULT;
+
+ALTER TABLE tasks
+ALTER COLUMN status TYPE task_status USING status::task_status;
+
+ALTER TABLE tasks
+ALTER COLUMN status
+SET DEFAULT 'TODO';
+
+DROP TABLE IF EXISTS workflow_transitions;
+
+DROP TABLE IF EXISTS workflow_states;
+
+DROP TABLE IF EXISTS workflows;
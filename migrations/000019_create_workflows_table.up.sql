@@ -0,0 +1,47 @@
+CREATE TABLE
+  IF NOT EXISTS workflows (
+    id serial PRIMARY KEY,
+    project_id INTEGER UNIQUE REFERENCES projects (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+  );
+
+-- a user has at most one workflow for the tasks outside of any project
+CREATE UNIQUE INDEX IF NOT EXISTS workflows_user_id_idx ON workflows (user_id)
+WHERE
+  project_id IS NULL;
+
+CREATE TABLE
+  IF NOT EXISTS workflow_states (
+    workflow_id INTEGER NOT NULL REFERENCES workflows (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    position INTEGER NOT NULL,
+    is_terminal boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (workflow_id, name)
+  );
+
+CREATE TABLE
+  IF NOT EXISTS workflow_transitions (
+    workflow_id INTEGER NOT NULL,
+    from_status text NOT NULL,
+    to_status text NOT NULL,
+    PRIMARY KEY (workflow_id, from_status, to_status),
+    FOREIGN KEY (workflow_id, from_status) REFERENCES workflow_states (workflow_id, name) ON DELETE CASCADE,
+    FOREIGN KEY (workflow_id, to_status) REFERENCES workflow_states (workflow_id, name) ON DELETE CASCADE
+  );
+
+-- statuses become workflow state names, the existing values are the states
+-- of the default workflow
+ALTER TABLE tasks
+ALTER COLUMN status
+DROP DEFAULT;
+
+ALTER TABLE tasks
+ALTER COLUMN status TYPE text USING status::text;
+
+ALTER TABLE tasks
+ALTER COLUMN status
+SET DEFAULT 'TODO';
+
+DROP TYPE IF EXISTS task_status;
@@ -0,0 +1,19 @@
+CREATE TABLE
+  IF NOT EXISTS custom_fields (
+    id serial PRIMARY KEY,
+    project_id INTEGER NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    key text NOT NULL,
+    name text NOT NULL,
+    type text NOT NULL,
+    options text[] NOT NULL DEFAULT '{}',
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (project_id, key),
+    CONSTRAINT custom_fields_type_check CHECK (type IN ('text', 'number', 'date', 'single_select', 'multi_select', 'url', 'user'))
+  );
+
+-- values are keyed by field key
+ALTER TABLE tasks
+ADD COLUMN custom_fields jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS tasks_custom_fields_idx ON tasks USING GIN (custom_fields jsonb_path_ops);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS task_comments_search_vector_idx;
+
+ALTER TABLE task_comments
+DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS tasks_search_vector_idx;
+
+ALTER TABLE tasks
+DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,24 @@
+-- events outlive the tasks they are about, so task_id has no foreign key.
+-- project_id and user_id are the task's when the event happened and scope
+-- who sees it once the task is gone.
+CREATE TABLE
+  IF NOT EXISTS task_events (
+    id bigserial PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    task_title text NOT NULL,
+    project_id INTEGER REFERENCES projects (id) ON DELETE SET NULL,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    actor_id INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    type text NOT NULL,
+    changes jsonb NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT task_events_type_check CHECK (type IN ('created', 'updated', 'status_changed', 'deleted'))
+  );
+
+CREATE INDEX IF NOT EXISTS task_events_task_id_idx ON task_events (task_id, id);
+
+CREATE INDEX IF NOT EXISTS task_events_project_id_idx ON task_events (project_id, id);
+
+CREATE INDEX IF NOT EXISTS task_events_user_id_idx ON task_events (user_id, id);
+
+CREATE INDEX IF NOT EXISTS task_events_actor_id_idx ON task_events (actor_id, id);
@@ -0,0 +1,12 @@
+DELETE FROM task_events WHERE type IN ('restored', 'purged');
+
+ALTER TABLE task_events
+DROP CONSTRAINT task_events_type_check,
+ADD CONSTRAINT task_events_type_check CHECK (type IN ('created', 'updated', 'status_changed', 'deleted'));
+
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS tasks_deleted_at_idx;
+
+ALTER TABLE tasks
+DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+ALTER TABLE tasks
+ADD COLUMN deleted_at timestamp(0) with time zone;
+
+-- the trash and the purge only look at trashed tasks
+CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks (deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE task_events
+DROP CONSTRAINT task_events_type_check,
+ADD CONSTRAINT task_events_type_check CHECK (type IN ('created', 'updated', 'status_changed', 'deleted', 'restored', 'purged'));
@@ -0,0 +1,13 @@
+DELETE FROM task_events WHERE type IN ('archived', 'unarchived');
+
+ALTER TABLE task_events
+DROP CONSTRAINT task_events_type_check,
+ADD CONSTRAINT task_events_type_check CHECK (type IN ('created', 'updated', 'status_changed', 'deleted', 'restored', 'purged'));
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS auto_archive_days;
+
+DROP INDEX IF EXISTS tasks_archived_at_idx;
+
+ALTER TABLE tasks
+DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,13 @@
+ALTER TABLE tasks
+ADD COLUMN archived_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS tasks_archived_at_idx ON tasks (archived_at) WHERE archived_at IS NOT NULL;
+
+-- done tasks are archived once they have been done for that many days, 0
+-- turns the rule off
+ALTER TABLE users
+ADD COLUMN auto_archive_days INTEGER NOT NULL DEFAULT 0 CONSTRAINT users_auto_archive_days_check CHECK (auto_archive_days >= 0);
+
+ALTER TABLE task_events
+DROP CONSTRAINT task_events_type_check,
+ADD CONSTRAINT task_events_type_check CHECK (type IN ('created', 'updated', 'status_changed', 'deleted', 'restored', 'purged', 'archived', 'unarchived'));
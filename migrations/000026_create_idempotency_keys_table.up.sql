@@ -0,0 +1,15 @@
+-- responses to requests sent with an Idempotency-Key, replayed when the
+-- request is retried. status is NULL while the first request is in flight.
+CREATE TABLE
+  IF NOT EXISTS idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    key text NOT NULL,
+    request_hash bytea NOT NULL,
+    status INTEGER,
+    headers jsonb,
+    body bytea,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, key)
+  );
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys (created_at);
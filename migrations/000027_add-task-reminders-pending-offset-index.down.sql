@@ -0,0 +1 @@
+DROP INDEX IF EXISTS task_reminders_pending_offset_idx;
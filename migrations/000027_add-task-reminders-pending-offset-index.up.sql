@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS task_reminders_pending_offset_idx ON task_reminders (task_id) WHERE sent_at IS NULL AND remind_at IS NULL;